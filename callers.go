@@ -0,0 +1,25 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+//go:build !errutil_nostack
+
+package errutil
+
+import "runtime"
+
+// stackCaptureEnabled - признак захвата стека вызовов,
+// отключается тегом сборки errutil_nostack
+const stackCaptureEnabled = true
+
+// callers возвращает счётчики команд стека вызовов, пропуская skip фреймов,
+// и признак того, что стек глубже depth и был обрезан
+func callers(skip int, depth int) ([]uintptr, bool) {
+	pcs := make([]uintptr, depth+1)
+	n := runtime.Callers(skip+1, pcs)
+
+	if n > depth {
+		return pcs[:depth:depth], true
+	}
+
+	return pcs[:n:n], false
+}
@@ -0,0 +1,16 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+//go:build errutil_nostack
+
+package errutil
+
+// stackCaptureEnabled - признак захвата стека вызовов,
+// отключается тегом сборки errutil_nostack
+const stackCaptureEnabled = false
+
+// callers при сборке с тегом errutil_nostack не захватывает стек:
+// ошибки создаются с пустым стеком, остальной API работает без изменений
+func callers(int, int) ([]uintptr, bool) {
+	return nil, false
+}
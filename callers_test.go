@@ -0,0 +1,6 @@
+//go:build !errutil_nostack
+
+package errutil_test
+
+// stackEnabled - признак захвата стека в текущей сборке
+const stackEnabled = true
@@ -0,0 +1,76 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Описания ошибок предметной области: код и сообщение для пользователя объявляются
+// один раз, экземпляры создаются с собственным стеком вызовов
+
+package errutil
+
+import "fmt"
+
+// Definition - описание ошибки предметной области.
+// Экземпляры, созданные через New и Wrap, совпадают с описанием в errors.Is.
+type Definition struct {
+	code    string
+	message string
+}
+
+// Define - объявление ошибки с кодом и сообщением для пользователя.
+// Сообщение может быть форматной строкой для параметров New и Wrapf.
+//
+//	var ErrOrderNotFound = errutil.Define("ORDER_NOT_FOUND", "Заказ не найден")
+func Define(code, message string) *Definition {
+	return &Definition{code: code, message: message}
+}
+
+// Code - получение кода ошибки
+func (d *Definition) Code() string {
+	return d.code
+}
+
+// Message - получение сообщения для пользователя
+func (d *Definition) Message() string {
+	return d.message
+}
+
+// Error - получение текстового представления описания
+func (d *Definition) Error() string {
+	return fmt.Sprintf("[%s] %s", d.code, d.message)
+}
+
+// New - создание экземпляра ошибки со стеком вызовов.
+// Параметры args подставляются в сообщение как в fmt.Sprintf.
+func (d *Definition) New(args ...any) error {
+	return d.newError(1, nil, args)
+}
+
+// Wrap - оборачивание ошибки err экземпляром ошибки со стеком вызовов.
+// При err == nil создаётся новый экземпляр.
+func (d *Definition) Wrap(err error) error {
+	return d.newError(1, err, nil)
+}
+
+// Wrapf - оборачивание ошибки err экземпляром ошибки с параметрами сообщения args
+func (d *Definition) Wrapf(err error, args ...any) error {
+	return d.newError(1, err, args)
+}
+
+// newError - создание экземпляра над err.
+// skip - количество фреймов над newError, не попадающих в стек.
+func (d *Definition) newError(skip int, err error, args []any) error {
+	f := Default()
+	err = newNode(err, segment{
+		kind:       segmentStack,
+		factory:    f,
+		code:       d.code,
+		stack:      f.captureStack(skip+1, d.code),
+		definition: d,
+	})
+
+	msg := d.message
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+
+	return newNode(err, segment{kind: segmentMessage, msg: msg})
+}
@@ -0,0 +1,75 @@
+package errutil_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+var (
+	errOrderNotFound = errutil.Define("ORDER_NOT_FOUND", "Заказ %d не найден")
+	errOrderLocked   = errutil.Define("ORDER_LOCKED", "Заказ заблокирован")
+)
+
+func TestDefine(t *testing.T) {
+	line := currentLine()
+	err := errOrderNotFound.New(42)
+
+	if !errors.Is(err, errOrderNotFound) {
+		t.Error("errors.Is(err, errOrderNotFound) = false")
+	}
+	if errors.Is(err, errOrderLocked) {
+		t.Error("errors.Is(err, errOrderLocked) = true")
+	}
+	if code := errutil.Code(err); code != "ORDER_NOT_FOUND" {
+		t.Errorf("Code() = %q", code)
+	}
+	if msg := errutil.Message(err); msg != "Заказ 42 не найден" {
+		t.Errorf("Message() = %q", msg)
+	}
+
+	if stackEnabled {
+		frames := errutil.StackTrace(err)
+		if len(frames) == 0 {
+			t.Fatal("StackTrace() is empty")
+		}
+		if last := frames[len(frames)-1]; last.Function != "TestDefine" || last.LineNumber != line+1 {
+			t.Errorf("last frame = %s:%d, want TestDefine:%d", last.Function, last.LineNumber, line+1)
+		}
+
+		// Каждый экземпляр получает собственный стек
+		if errutil.Stack(errOrderNotFound.New(1)) == errutil.Stack(err) {
+			t.Error("instances share stack")
+		}
+	}
+
+	wrapped := errutil.WithDevMessage(err, "load order")
+	if !errors.Is(wrapped, errOrderNotFound) {
+		t.Error("errors.Is(wrapped, errOrderNotFound) = false")
+	}
+}
+
+func TestDefineWrap(t *testing.T) {
+	err := errOrderLocked.Wrap(io.EOF)
+
+	if !errors.Is(err, errOrderLocked) || !errors.Is(err, io.EOF) {
+		t.Errorf("errors.Is() = false for %v", err)
+	}
+	if err.Error() != "[ORDER_LOCKED] EOF (Заказ заблокирован)" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if errutil.Cause(err) != io.EOF {
+		t.Errorf("Cause() = %v", errutil.Cause(err))
+	}
+
+	if err := errOrderNotFound.Wrapf(nil, 7); errutil.Message(err) != "Заказ 7 не найден" || !errors.Is(err, errOrderNotFound) {
+		t.Errorf("Wrapf(nil) = %v", err)
+	}
+
+	stripped := errutil.Transform(err, errutil.StripStacks())
+	if !errors.Is(stripped, errOrderLocked) {
+		t.Error("errors.Is after Transform = false")
+	}
+}
@@ -1,138 +1,253 @@
 // Copyright 2024-2025 Kontora13. All rights reserved.
 // Licensed under the Apache License, Version 2.0
 
-// Описания различных врапперов ошибок, используемых
-// в пакете для работы с ошибками
+// Описание ошибки пакета: неизменяемый узел с плоским списком сегментов
+// (код, стек вызовов, сообщения, поля), добавляемых при каждом оборачивании
 
 package errutil
 
 import (
-	"bytes"
+	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
+	"sync/atomic"
 )
 
-// errWithCode - ошибка с контекстом кода ошибки
-type errWithCode struct {
-	code  string
-	cause error
-}
+// segmentKind - вид сегмента ошибки
+type segmentKind uint8
 
-// Error - получение текстового представления ошибки
-func (e *errWithCode) Error() string {
-	return errorString(e)
-}
+const (
+	segmentCode segmentKind = iota
+	segmentStack
+	segmentMessage
+	segmentDev
+	segmentFields
+)
 
-// Cause - распаковка исходной ошибки
-func (e *errWithCode) Cause() error {
-	return e.cause
-}
+// segment - один уровень цепочки ошибки: код, стек вызовов,
+// сообщение для пользователя, сообщения для разработчика или поля контекста
+type segment struct {
+	kind segmentKind
+	code string
+
+	// Код подставлен по умолчанию, а не задан явно
+	implicit bool
+
+	stack   *stack
+	factory *Factory
+	msg     string
+	dev     []string
+	fields  map[string]any
+
+	// Описание ошибки, экземпляр которой создан сегментом (Definition)
+	definition *Definition
+
+	// Сегменты, из которых получен сегмент при преобразовании цепочки (Transform)
+	origins []*segment
+}
+
+// devMessage - сообщения для разработчика сегмента одной строкой
+func (s *segment) devMessage() string {
+	return strings.Join(s.dev, ": ")
+}
+
+// chain - общие для цепочки ошибок исходная ошибка и массив сегментов.
+// tail - количество занятых элементов: добавить сегмент без копирования
+// может только узел, содержащий все занятые сегменты.
+type chain struct {
+	root error
+	tail atomic.Int64
+	buf  [4]*segment
+}
+
+// node - ошибка пакета errutil.
+// Сегменты хранятся от внутреннего к внешнему и разделяются между узлами цепочки:
+// оборачивание последнего узла добавляет сегмент в общий массив без копирования,
+// оборачивание промежуточного узла копирует его сегменты.
+// Внешний сегмент хранится в самом узле.
+// Узел неизменяем, текстовое представление вычисляется один раз.
+type node struct {
+	seg   segment
+	segs  []*segment
+	chain *chain
+	text  atomic.Pointer[string]
+
+	// Экземпляр, через который создан ближайший к внешнему сегмент узла, или nil
+	factory *Factory
+}
+
+// newNode - создание ошибки, оборачивающей root сегментом seg
+func newNode(root error, seg segment) *node {
+	e := &node{seg: seg, factory: seg.factory}
+
+	if parent, ok := root.(*node); ok {
+		if e.factory == nil {
+			e.factory = parent.factory
+		}
+
+		n := int64(len(parent.segs))
+		if parent.chain.tail.CompareAndSwap(n, n+1) {
+			e.chain = parent.chain
+			e.segs = append(parent.segs, &e.seg)
+			return e
+		}
+
+		e.chain = &chain{root: parent.chain.root}
+		e.chain.tail.Store(n + 1)
+		e.segs = append(make([]*segment, 0, max(len(parent.segs)+1, len(e.chain.buf))*2), parent.segs...)
+		e.segs = append(e.segs, &e.seg)
+		return e
+	}
 
-// Code - получение кода ошибки
-func (e *errWithCode) Code() string {
-	return e.code
+	e.chain = &chain{root: root}
+	e.chain.tail.Store(1)
+	e.segs = append(e.chain.buf[:0], &e.seg)
+
+	return e
 }
 
-/*
-----------
-*/
+// root - исходная ошибка, обёрнутая сегментами, или nil
+func (e *node) root() error {
+	return e.chain.root
+}
 
-// errWithStack - ошибка с Callers trace ошибки
-type errWithStack struct {
-	code       string
-	stacktrace []StackFrame
-	cause      error
+// top - внешний сегмент ошибки
+func (e *node) top() *segment {
+	return e.segs[len(e.segs)-1]
 }
 
 // Error - получение текстового представления ошибки
-func (e *errWithStack) Error() string {
-	return errorString(e)
-}
+func (e *node) Error() string {
+	if text := e.text.Load(); text != nil {
+		return *text
+	}
 
-// Stack - получение Callers trace ошибки
-func (e *errWithStack) Stack() string {
-	buf := bytes.Buffer{}
+	text := errorString(e)
+	e.text.Store(&text)
 
-	for _, frame := range e.stackFrames() {
-		buf.WriteString(frame.String())
-	}
+	return text
+}
 
-	return string(buf.Bytes())
+// Format - форматирование ошибки для пакета fmt, %+v выводит цепочку со стеком
+func (e *node) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
 }
 
-func (e *errWithStack) StackTrace() []StackFrame {
-	return e.stacktrace
+// MarshalJSON - сериализация цепочки ошибок в JSON, восстанавливается через Decode
+func (e *node) MarshalJSON() ([]byte, error) {
+	return marshalError(e)
 }
 
-// Cause - распаковка исходной ошибки
-func (e *errWithStack) Cause() error {
-	return e.cause
+// LogValue - представление ошибки в виде группы атрибутов slog
+func (e *node) LogValue() slog.Value {
+	return SlogValue(e, false)
 }
 
-// Code - получение кода ошибки
-func (e *errWithStack) Code() string {
-	return e.code
+// Cause - распаковка исходной ошибки
+func (e *node) Cause() error {
+	return e.Unwrap()
 }
 
-// stackFrames - возвращает массив фреймов, содержащих информацию о стеке.
-func (e *errWithStack) stackFrames() []StackFrame {
-	return e.stacktrace
+// Unwrap - распаковка исходной ошибки для errors.Is и errors.As.
+// Возвращает ошибку без внешнего сегмента, разделяющую с e массив сегментов.
+func (e *node) Unwrap() error {
+	n := len(e.segs) - 1
+	if n == 0 {
+		return e.chain.root
+	}
+
+	return e.prefix(n)
 }
 
-func newErrorStack() []StackFrame {
-	return NewStackTrace(3)
+// Is - сравнение с ошибкой для errors.Is: ошибка совпадает с любой ошибкой,
+// из которой получена оборачиванием или преобразованием (Transform),
+// уровень экземпляра Definition - с описанием, а уровень с явно заданным кодом -
+// с ошибкой с тем же явно заданным кодом
+func (e *node) Is(target error) bool {
+	if v, ok := target.(viewer); ok {
+		target = v.viewNode()
+	}
+	if d, ok := target.(*Definition); ok {
+		return e.top().definition == d
+	}
+	if t, ok := target.(*node); ok {
+		n := len(t.segs)
+		if n <= len(e.segs) && t.segs[n-1] == e.segs[n-1] {
+			return true
+		}
+		if slices.Contains(e.top().origins, t.segs[n-1]) {
+			return true
+		}
+	}
+
+	s := e.top()
+	if s.implicit || (s.kind != segmentCode && s.kind != segmentStack) {
+		return false
+	}
+
+	return isSameCode(s.code, target)
 }
 
-/*
-----------
-*/
+// stackSegment - получение внешнего сегмента со стеком вызовов
+func (e *node) stackSegment() (*segment, bool) {
+	for i := len(e.segs) - 1; i >= 0; i-- {
+		if s := e.segs[i]; s.kind == segmentStack {
+			return s, true
+		}
+	}
 
-// errWithMessage - ошибка, содержащая сообщение для пользователя
-type errWithMessage struct {
-	msg   string
-	cause error
+	return nil, false
 }
 
-// Message - получение сообщения, содержащегося в ошибке
-func (e *errWithMessage) Message() string {
-	return e.msg
+// Code - получение кода ошибки
+func (e *node) Code() string {
+	return Code(e)
 }
 
-// Cause - распаковка исходной ошибки
-func (e *errWithMessage) Cause() error {
-	return e.cause
+// Message - получение сообщения для пользователя
+func (e *node) Message() string {
+	return Message(e)
 }
 
-// Error - получение текстового представления ошибки
-func (e *errWithMessage) Error() string {
-	return errorString(e)
+// DevMessage - получение сообщения для разработчика
+func (e *node) DevMessage() string {
+	return DevMessage(e)
 }
 
-/*
-----------
-*/
+// DevMessages - получение списка сообщений для разработчика
+func (e *node) DevMessages() []string {
+	return DevMessages(e)
+}
 
-// errWithDevMessage - ошибка, содержащая сообщения для разработчика
-type errWithDevMessage struct {
-	dev   []string
-	cause error
+// Stack - получение Callers trace ошибки
+func (e *node) Stack() string {
+	return Stack(e)
 }
 
-// DevMessage - получение dev-сообщения, содержащихся в ошибке
-func (e *errWithDevMessage) DevMessage() string {
-	return strings.Join(e.dev, ": ")
+// StackTrace - получение фреймов стека, фреймы строятся при первом обращении
+func (e *node) StackTrace() []StackFrame {
+	return StackTrace(e)
 }
 
-// DevMessages - получение dev-сообщения, содержащихся в ошибке в виде слайса
-func (e *errWithDevMessage) DevMessages() []string {
-	return e.dev
+// StackTruncated - признак того, что стек обрезан по максимальной глубине
+func (e *node) StackTruncated() bool {
+	return StackTruncated(e)
 }
 
-// Cause - распаковка исходной ошибки
-func (e *errWithDevMessage) Cause() error {
-	return e.cause
+// Fields - получение полей контекста
+func (e *node) Fields() map[string]any {
+	return Fields(e)
 }
 
-// Error - получение текстового представления ошибки
-func (e *errWithDevMessage) Error() string {
-	return errorString(e)
+// prefix - ошибка из n внутренних сегментов e
+func (e *node) prefix(n int) *node {
+	if n == len(e.segs) {
+		return e
+	}
+
+	return &node{
+		segs:  e.segs[:n:n],
+		chain: e.chain,
+	}
 }
@@ -0,0 +1,151 @@
+package errutil_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestErrorBranches(t *testing.T) {
+	base := errutil.WithMessage(errutil.NewWithCode("BASE", "dev"), "base")
+
+	// Оборачивание одной ошибки в разных местах не влияет на другие ветки
+	x := errutil.WithMessage(base, "x")
+	y := errutil.WithMessage(base, "y")
+	xx := errutil.WithDevMessage(x, "xx")
+
+	if got := errutil.Messages(x); !slices.Equal(got, []string{"x", "base"}) {
+		t.Errorf("Messages(x) = %q", got)
+	}
+	if got := errutil.Messages(y); !slices.Equal(got, []string{"y", "base"}) {
+		t.Errorf("Messages(y) = %q", got)
+	}
+	if got := xx.Error(); got != "[BASE] xx, dev (x: base)" {
+		t.Errorf("xx.Error() = %q", got)
+	}
+	if got := base.Error(); got != "[BASE] dev (base)" {
+		t.Errorf("base.Error() = %q", got)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	base := errutil.NewWithCode("A", "dev")
+	other := errutil.NewWithCode("B", "dev")
+	err := errutil.WithDevMessage(errutil.WithMessage(base, "msg"), "outer")
+
+	if !errors.Is(err, base) {
+		t.Error("errors.Is(err, base) = false")
+	}
+	if errors.Is(err, other) {
+		t.Error("errors.Is(err, other) = true")
+	}
+	if !errors.Is(err, errors.Unwrap(err)) {
+		t.Error("errors.Is(err, errors.Unwrap(err)) = false")
+	}
+
+	wrapped := errutil.WithMessage(errutil.WithStack(sql.ErrNoRows), "not found")
+	if !errors.Is(wrapped, sql.ErrNoRows) {
+		t.Error("errors.Is(wrapped, sql.ErrNoRows) = false")
+	}
+	if errutil.Cause(wrapped) != sql.ErrNoRows {
+		t.Errorf("Cause() = %v", errutil.Cause(wrapped))
+	}
+}
+
+func TestErrorIsDefaultCode(t *testing.T) {
+	notFound := errutil.New("not found")
+
+	// Код по умолчанию не делает разные ошибки New равными
+	if errors.Is(errutil.New("db broke"), notFound) {
+		t.Error("errors.Is(New(), New()) = true")
+	}
+	if errors.Is(errutil.WithMessage(errutil.Newf("id=%d", 1), "msg"), notFound) {
+		t.Error("errors.Is(wrapped New(), New()) = true")
+	}
+	if errors.Is(errutil.NewWithCode(errutil.DefaultCode, "db broke"), notFound) {
+		t.Error("errors.Is(NewWithCode(DefaultCode), New()) = true")
+	}
+	if errors.Is(errutil.New("db broke"), errutil.NewWithCode(errutil.DefaultCode)) {
+		t.Error("errors.Is(New(), NewWithCode(DefaultCode)) = true")
+	}
+	if errors.Is(errutil.NewSkip(0, "db broke"), errutil.NewWithCode(errutil.DefaultCode)) {
+		t.Error("errors.Is(NewSkip(), NewWithCode(DefaultCode)) = true")
+	}
+	if errors.Is(errutil.NewSkipf(0, "id=%d", 1), notFound) {
+		t.Error("errors.Is(NewSkipf(), New()) = true")
+	}
+
+	// Явно заданные коды сравниваются
+	if !errors.Is(errutil.NewWithCode(errutil.CodeUser, "bad input"), errutil.NewWithCode(errutil.CodeUser)) {
+		t.Error("errors.Is(NewWithCode(), NewWithCode()) = false")
+	}
+	if !errors.Is(errutil.WithCode(errutil.New("db broke"), "DB"), errutil.NewWithCode("DB")) {
+		t.Error("errors.Is(WithCode(), NewWithCode()) = false")
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	err := errutil.WithField(errutil.WithMessage(errutil.New("dev"), "msg"), "k", 1)
+
+	// New создаёт два уровня (стек и dev-сообщение), далее по уровню на каждый вызов
+	layers := 0
+	for e := error(err); e != nil; e = errors.Unwrap(e) {
+		layers++
+	}
+	if layers != 4 {
+		t.Errorf("layers = %d, want 4", layers)
+	}
+
+	inner := errors.Unwrap(err)
+	if got := errutil.Fields(inner); len(got) != 0 {
+		t.Errorf("Fields(inner) = %v", got)
+	}
+	if got := errutil.Message(inner); got != "msg" {
+		t.Errorf("Message(inner) = %q", got)
+	}
+}
+
+func TestErrorConcurrentWrap(t *testing.T) {
+	base := errutil.New("base")
+
+	results := make([]error, 50)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := base
+			for j := range 10 {
+				err = errutil.WithMessagef(err, "%d-%d", i, j)
+			}
+			results[i] = err
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range results {
+		msgs := errutil.Messages(err)
+		if len(msgs) != 10 {
+			t.Fatalf("Messages(#%d) = %q", i, msgs)
+		}
+		for j, msg := range msgs {
+			if want := fmt.Sprintf("%d-%d", i, 9-j); msg != want {
+				t.Errorf("Messages(#%d)[%d] = %q, want %q", i, j, msg, want)
+			}
+		}
+	}
+}
+
+func TestErrorStringCached(t *testing.T) {
+	err := errutil.WithMessage(errutil.New("dev"), "msg")
+
+	if unsafe.StringData(err.Error()) != unsafe.StringData(err.Error()) {
+		t.Error("Error() is not cached")
+	}
+}
@@ -8,7 +8,8 @@ package errutil
 
 import "fmt"
 
-var (
+// Значения по умолчанию для незаполненных полей Config.DefaultCode и Config.DefaultUserMessage
+const (
 	DefaultCode        = CodeCritical
 	DefaultUserMessage = "Упс, что-то пошло не так. Попробуйте позже..."
 )
@@ -21,52 +22,32 @@ const (
 
 // New - конструктор ошибки из списка строк
 func New(message ...string) error {
-	err := &errWithStack{
-		code:       DefaultCode,
-		stacktrace: newErrorStack(),
-	}
-
-	return &errWithDevMessage{
-		cause: err,
-		dev:   message,
-	}
+	return Default().newError(1, "", message)
 }
 
 // Newf - конструктор ошибки из форматной строки с параметрами
 func Newf(format string, args ...interface{}) error {
-	err := &errWithStack{
-		code:       DefaultCode,
-		stacktrace: newErrorStack(),
-	}
+	return Default().newError(1, "", []string{fmt.Sprintf(format, args...)})
+}
 
-	return &errWithDevMessage{
-		cause: err,
-		dev:   []string{fmt.Sprintf(format, args...)},
-	}
+// NewSkip - конструктор ошибки из списка строк, пропускающий skip дополнительных
+// фреймов стека над вызывающей функцией (например, для функций-обёрток)
+func NewSkip(skip int, message ...string) error {
+	return Default().newError(1+max(skip, 0), "", message)
+}
+
+// NewSkipf - конструктор ошибки из форматной строки с параметрами, пропускающий skip
+// дополнительных фреймов стека над вызывающей функцией
+func NewSkipf(skip int, format string, args ...interface{}) error {
+	return Default().newError(1+max(skip, 0), "", []string{fmt.Sprintf(format, args...)})
 }
 
 // NewWithCode - конструктор ошибки из списка строк с указанием кода ошибки
 func NewWithCode(code string, message ...string) error {
-	err := &errWithStack{
-		code:       code,
-		stacktrace: newErrorStack(),
-	}
-
-	return &errWithDevMessage{
-		cause: err,
-		dev:   message,
-	}
+	return Default().newError(1, code, message)
 }
 
 // NewWithCodef - конструктор ошибки из форматной строки с параметрами с указанием кода ошибки
 func NewWithCodef(code string, format string, args ...interface{}) error {
-	err := &errWithStack{
-		code:       code,
-		stacktrace: newErrorStack(),
-	}
-
-	return &errWithDevMessage{
-		cause: err,
-		dev:   []string{fmt.Sprintf(format, args...)},
-	}
+	return Default().newError(1, code, []string{fmt.Sprintf(format, args...)})
 }
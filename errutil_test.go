@@ -82,3 +82,14 @@ func BenchmarkErrorStack(b *testing.B) {
 		err = errutil.WithStack(err)
 	}
 }
+
+func BenchmarkErrorString(b *testing.B) {
+	err := errutil.New("test user error", "one")
+	for i := 0; i < 100; i++ {
+		err = errutil.WithMessagef(errutil.WithDevMessagef(err, "error %d", i), "message %d", i)
+	}
+
+	for i := 0; i < b.N; i++ {
+		_ = err.Error()
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Настройки создания ошибок в виде отдельного экземпляра (Factory)
+// вместо изменяемых глобальных переменных пакета
+
+package errutil
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"strings"
+	"sync/atomic"
+)
+
+// Config - настройки создания и отображения ошибок.
+// Незаполненные поля заменяются значениями по умолчанию.
+type Config struct {
+	// Код ошибки по умолчанию, см. DefaultCode
+	DefaultCode string
+
+	// Сообщение для пользователя по умолчанию, см. DefaultUserMessage
+	DefaultUserMessage string
+
+	// Максимальная глубина стека, см. MaxStackTraceDepth
+	MaxStackTraceDepth int
+
+	// Префикс модуля, по которому определяется свойство InApp фрейма.
+	// Если не задан, используется основной модуль из debug.ReadBuildInfo, см. также SetFrameFilter.
+	StackTraceAppPrefix string
+
+	// Максимальное количество уровней цепочки ошибок экземпляра, обходимых функциями пакета,
+	// более глубокие уровни отбрасываются с пометкой. По умолчанию 10000.
+	MaxChainDepth int
+
+	// Приоритет кодов при определении кода мультиошибки и в стратегии CodeByPrecedence:
+	// чем раньше код в списке, тем выше его приоритет, коды вне списка имеют наименьший приоритет.
+	// По умолчанию PANIC, CRITICAL, USER.
+	CodePrecedence []string
+
+	// Отладочный режим Problem: в ответ добавляются dev-сообщения и стек вызовов
+	ProblemDebug bool
+
+	// Максимальный размер тела ответа, читаемого ParseProblem. По умолчанию 1 МиБ.
+	MaxProblemSize int64
+
+	// Максимальное количество файлов в кэше исходного кода. По умолчанию 64.
+	// Кэш общий для пакета, применяется значение экземпляра, установленного через SetDefault.
+	SourceCacheSize int
+
+	// Стратегия определения кода ошибки, когда цепочка содержит несколько кодов.
+	// Применяется в Code, тексте ошибки, HTTP/gRPC-статусах и логах. Если не задана, используется CodeOutermost.
+	CodeResolver CodeResolver
+
+	// Политика захвата стека вызовов. Если не задана, стек захватывается всегда.
+	StackPolicy StackPolicy
+
+	// Функция получения текстового представления ошибки (Error).
+	// Если не задана, используется DefaultRenderer.
+	Renderer func(err error) string
+}
+
+// Factory - экземпляр настроек для создания ошибок.
+// Методы повторяют функции пакета New, Newf, WithMessage и другие.
+// Ошибки, созданные через Factory, сохраняют ссылку на неё, поэтому
+// их текст и код по умолчанию определяются её настройками.
+type Factory struct {
+	cfg Config
+}
+
+// NewFactory - создание экземпляра с настройками cfg.
+// Незаполненные поля заполняются значениями по умолчанию.
+func NewFactory(cfg Config) *Factory {
+	if cfg.DefaultCode == "" {
+		cfg.DefaultCode = DefaultCode
+	}
+	if cfg.DefaultUserMessage == "" {
+		cfg.DefaultUserMessage = DefaultUserMessage
+	}
+	if cfg.MaxStackTraceDepth <= 0 {
+		cfg.MaxStackTraceDepth = MaxStackTraceDepth
+	}
+	if cfg.MaxChainDepth <= 0 {
+		cfg.MaxChainDepth = 10000
+	}
+	if cfg.CodePrecedence == nil {
+		cfg.CodePrecedence = []string{CodePanic, CodeCritical, CodeUser}
+	}
+	if cfg.MaxProblemSize <= 0 {
+		cfg.MaxProblemSize = 1 << 20
+	}
+	if cfg.SourceCacheSize <= 0 {
+		cfg.SourceCacheSize = 64
+	}
+
+	return &Factory{cfg: cfg}
+}
+
+// Config - получение настроек экземпляра
+func (f *Factory) Config() Config {
+	return f.cfg
+}
+
+// defaultFactory - экземпляр, используемый функциями пакета
+var defaultFactory atomic.Pointer[Factory]
+
+// Default - получение экземпляра, используемого функциями пакета
+func Default() *Factory {
+	if f := defaultFactory.Load(); f != nil {
+		return f
+	}
+
+	return zeroFactory
+}
+
+// SetDefault - атомарная замена экземпляра, используемого функциями пакета.
+// При f == nil восстанавливается экземпляр с настройками по умолчанию.
+func SetDefault(f *Factory) {
+	defaultFactory.Store(f)
+}
+
+// zeroFactory - экземпляр с настройками по умолчанию
+var zeroFactory = NewFactory(Config{})
+
+// DefaultRenderer - текстовое представление ошибки по умолчанию:
+// "[код] сообщения разработчика (сообщение для пользователя)"
+func DefaultRenderer(err error) string {
+	return renderError(err)
+}
+
+/*
+----------
+*/
+
+// New - конструктор ошибки из списка строк
+func (f *Factory) New(message ...string) error {
+	return f.newError(1, "", message)
+}
+
+// Newf - конструктор ошибки из форматной строки с параметрами
+func (f *Factory) Newf(format string, args ...interface{}) error {
+	return f.newError(1, "", []string{fmt.Sprintf(format, args...)})
+}
+
+// NewSkip - конструктор ошибки из списка строк, пропускающий skip дополнительных
+// фреймов стека над вызывающей функцией
+func (f *Factory) NewSkip(skip int, message ...string) error {
+	return f.newError(1+max(skip, 0), "", message)
+}
+
+// NewSkipf - конструктор ошибки из форматной строки с параметрами, пропускающий skip
+// дополнительных фреймов стека над вызывающей функцией
+func (f *Factory) NewSkipf(skip int, format string, args ...interface{}) error {
+	return f.newError(1+max(skip, 0), "", []string{fmt.Sprintf(format, args...)})
+}
+
+// NewWithCode - конструктор ошибки из списка строк с указанием кода ошибки
+func (f *Factory) NewWithCode(code string, message ...string) error {
+	return f.newError(1, code, message)
+}
+
+// NewWithCodef - конструктор ошибки из форматной строки с параметрами с указанием кода ошибки
+func (f *Factory) NewWithCodef(code string, format string, args ...interface{}) error {
+	return f.newError(1, code, []string{fmt.Sprintf(format, args...)})
+}
+
+// WithCode - добавление кода ошибки
+func (f *Factory) WithCode(err error, code string) error {
+	return f.withCode(1, err, code)
+}
+
+// WithStack - добавление стека вызовов
+func (f *Factory) WithStack(err error) error {
+	return f.withStack(1, err)
+}
+
+// WithStackSkip - добавление стека вызовов с пропуском skip дополнительных
+// фреймов над вызывающей функцией
+func (f *Factory) WithStackSkip(err error, skip int) error {
+	return f.withStack(1+max(skip, 0), err)
+}
+
+// WithMessage - добавление сообщения для пользователя
+func (f *Factory) WithMessage(err error, msg ...string) error {
+	return f.withMessage(1, err, strings.Join(msg, ": "))
+}
+
+// WithMessagef - добавление сообщения для пользователя из форматной строки с параметрами
+func (f *Factory) WithMessagef(err error, format string, args ...interface{}) error {
+	return f.withMessage(1, err, fmt.Sprintf(format, args...))
+}
+
+// WithDevMessage - добавление сообщений для разработчика
+func (f *Factory) WithDevMessage(err error, msg ...string) error {
+	return f.withDevMessage(1, err, msg)
+}
+
+// WithDevMessagef - добавление сообщения для разработчика из форматной строки с параметрами
+func (f *Factory) WithDevMessagef(err error, format string, args ...interface{}) error {
+	return f.withDevMessage(1, err, []string{fmt.Sprintf(format, args...)})
+}
+
+// WithField - добавление поля контекста
+func (f *Factory) WithField(err error, key string, value any) error {
+	return f.withFields(1, err, map[string]any{key: value})
+}
+
+// WithFields - добавление полей контекста
+func (f *Factory) WithFields(err error, fields map[string]any) error {
+	return f.withFields(1, err, fields)
+}
+
+/*
+----------
+*/
+
+// newError - создание ошибки с кодом и сообщениями для разработчика.
+// Пустой code заменяется кодом по умолчанию, который не участвует в сравнении errors.Is.
+// skip - количество фреймов над newError, не попадающих в стек.
+func (f *Factory) newError(skip int, code string, dev []string) error {
+	implicit := code == ""
+	if implicit {
+		code = f.defaultCode()
+	}
+
+	err := newNode(nil, segment{kind: segmentStack, implicit: implicit, factory: f, code: code, stack: f.captureStack(skip+1, code)})
+
+	return newNode(err, segment{kind: segmentDev, dev: dev})
+}
+
+// orNew - замена nil ошибки на новую ошибку с кодом по умолчанию
+func (f *Factory) orNew(skip int, err error) error {
+	if err != nil {
+		return err
+	}
+
+	code := f.defaultCode()
+
+	return newNode(nil, segment{kind: segmentStack, implicit: true, factory: f, code: code, stack: f.captureStack(skip+1, code)})
+}
+
+func (f *Factory) withCode(skip int, err error, code string) error {
+	if err == nil {
+		return newNode(nil, segment{kind: segmentStack, factory: f, code: code, stack: f.captureStack(skip+1, code)})
+	}
+
+	return newNode(err, segment{kind: segmentCode, code: code})
+}
+
+func (f *Factory) withStack(skip int, err error) error {
+	var code string
+	if f.cfg.StackPolicy != nil {
+		code = f.code(err)
+	}
+
+	return newNode(err, segment{kind: segmentStack, factory: f, stack: f.captureStack(skip+1, code)})
+}
+
+func (f *Factory) withMessage(skip int, err error, msg string) error {
+	return newNode(f.orNew(skip+1, err), segment{kind: segmentMessage, msg: msg})
+}
+
+func (f *Factory) withDevMessage(skip int, err error, dev []string) error {
+	return newNode(f.orNew(skip+1, err), segment{kind: segmentDev, dev: dev})
+}
+
+func (f *Factory) withFields(skip int, err error, fields map[string]any) error {
+	return newNode(f.orNew(skip+1, err), segment{kind: segmentFields, fields: maps.Clone(fields)})
+}
+
+// captureStack - захват стека вызовов согласно политике StackPolicy.
+// skip - количество фреймов над captureStack, не попадающих в стек.
+func (f *Factory) captureStack(skip int, code string) *stack {
+	if !stackCaptureEnabled {
+		return nil
+	}
+	if policy := f.cfg.StackPolicy; policy != nil && !policy(code) {
+		return nil
+	}
+
+	return f.newStack(callers(skip+2, f.maxStackTraceDepth()))
+}
+
+// newStack - создание стека вызовов с префиксом модуля приложения экземпляра
+func (f *Factory) newStack(pcs []uintptr, truncated bool) *stack {
+	s := newStack(pcs, truncated)
+	if s != nil {
+		s.appPrefix = f.cfg.StackTraceAppPrefix
+	}
+
+	return s
+}
+
+// code - получение кода ошибки с учётом стратегии и кода по умолчанию экземпляра
+func (f *Factory) code(err error) string {
+	if code, ok := resolveCode(err, f.codeResolver(), f.codePrecedence()); ok {
+		return code
+	}
+
+	return f.defaultCode()
+}
+
+// render - получение текстового представления ошибки
+func (f *Factory) render(err error) string {
+	if f.cfg.Renderer != nil {
+		return f.cfg.Renderer(err)
+	}
+
+	return renderError(err)
+}
+
+// Получение настроек с заменой незаполненных значений настройками zeroFactory
+// для экземпляров, созданных без NewFactory
+
+func (f *Factory) defaultCode() string {
+	return cmp.Or(f.cfg.DefaultCode, zeroFactory.cfg.DefaultCode)
+}
+
+func (f *Factory) defaultUserMessage() string {
+	return cmp.Or(f.cfg.DefaultUserMessage, zeroFactory.cfg.DefaultUserMessage)
+}
+
+func (f *Factory) maxStackTraceDepth() int {
+	return cmp.Or(max(f.cfg.MaxStackTraceDepth, 0), zeroFactory.cfg.MaxStackTraceDepth)
+}
+
+func (f *Factory) codeResolver() CodeResolver {
+	if f.cfg.CodeResolver != nil {
+		return f.cfg.CodeResolver
+	}
+
+	return CodeOutermost()
+}
+
+func (f *Factory) maxChainDepth() int {
+	return cmp.Or(max(f.cfg.MaxChainDepth, 0), zeroFactory.cfg.MaxChainDepth)
+}
+
+func (f *Factory) codePrecedence() []string {
+	if f.cfg.CodePrecedence != nil {
+		return f.cfg.CodePrecedence
+	}
+
+	return zeroFactory.cfg.CodePrecedence
+}
+
+func (f *Factory) maxProblemSize() int64 {
+	return cmp.Or(max(f.cfg.MaxProblemSize, 0), zeroFactory.cfg.MaxProblemSize)
+}
+
+func (f *Factory) sourceCacheSize() int {
+	return cmp.Or(max(f.cfg.SourceCacheSize, 0), zeroFactory.cfg.SourceCacheSize)
+}
+
+// factoryOf - получение экземпляра, через который создана ошибка.
+// Для ошибок, созданных не через Factory, возвращается Default.
+func factoryOf(err error) *Factory {
+	f := Default()
+
+	walk(err, func(l *layer) walkAction {
+		if l.seg != nil && l.seg.factory != nil {
+			f = l.seg.factory
+			return walkStop
+		}
+		if l.multi != nil {
+			return walkStop
+		}
+
+		return walkNext
+	}, nil)
+
+	return f
+}
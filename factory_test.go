@@ -0,0 +1,171 @@
+package errutil_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestFactoryConfig(t *testing.T) {
+	f := errutil.NewFactory(errutil.Config{
+		DefaultCode:        "LIB",
+		DefaultUserMessage: "library failed",
+	})
+
+	err := f.New("dev")
+	if code := errutil.Code(err); code != "LIB" {
+		t.Errorf("Code() = %q, want %q", code, "LIB")
+	}
+	if msg := errutil.UserMessage(err); msg != "library failed" {
+		t.Errorf("UserMessage() = %q, want %q", msg, "library failed")
+	}
+	if err.Error() != "[LIB] dev" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	// Настройки экземпляра не влияют на функции пакета
+	if code := errutil.Code(errutil.New("dev")); code != errutil.DefaultCode {
+		t.Errorf("package New() code = %q, want %q", code, errutil.DefaultCode)
+	}
+
+	wrapped := errutil.WithMessage(f.WithDevMessage(f.Newf("id=%d", 1), "load"), "msg")
+	if wrapped.Error() != "[LIB] load, id=1 (msg)" {
+		t.Errorf("Error() = %q", wrapped.Error())
+	}
+
+	if err := f.WithMessage(nil, "msg"); errutil.Code(err) != "LIB" || stackEnabled && len(errutil.StackTrace(err)) == 0 {
+		t.Errorf("WithMessage(nil) = %q without stack", err)
+	}
+}
+
+func TestFactoryConfigDefaults(t *testing.T) {
+	cfg := errutil.NewFactory(errutil.Config{}).Config()
+
+	if cfg.DefaultCode != errutil.DefaultCode || cfg.DefaultUserMessage != errutil.DefaultUserMessage {
+		t.Errorf("Config() = %q, %q", cfg.DefaultCode, cfg.DefaultUserMessage)
+	}
+	if cfg.MaxStackTraceDepth != errutil.MaxStackTraceDepth {
+		t.Errorf("Config().MaxStackTraceDepth = %d", cfg.MaxStackTraceDepth)
+	}
+	if cfg.ProblemDebug || cfg.StackTraceAppPrefix != "" {
+		t.Errorf("Config() = %+v", cfg)
+	}
+}
+
+func TestFactoryStackLine(t *testing.T) {
+	requireStack(t)
+
+	f := errutil.NewFactory(errutil.Config{})
+
+	tests := []struct {
+		name string
+		line int
+		err  error
+	}{
+		{"New", currentLine(), errutil.New("test")},
+		{"Factory.New", currentLine(), f.New("test")},
+		{"Factory.NewWithCodef", currentLine(), f.NewWithCodef("C", "%d", 1)},
+		{"Factory.WithStack", currentLine(), f.WithStack(errors.New("test"))},
+		{"Factory.WithFields", currentLine(), f.WithFields(nil, map[string]any{"k": 1})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := errutil.StackTrace(tt.err)
+			if len(frames) == 0 {
+				t.Fatal("StackTrace() is empty")
+			}
+			last := frames[len(frames)-1]
+			if last.Function != "TestFactoryStackLine" || last.LineNumber != tt.line {
+				t.Errorf("last frame = %s:%d, want TestFactoryStackLine:%d", last.Function, last.LineNumber, tt.line)
+			}
+		})
+	}
+}
+
+func TestFactoryStackPolicy(t *testing.T) {
+	f := errutil.NewFactory(errutil.Config{StackPolicy: errutil.StackNever()})
+
+	err := f.NewWithCode(errutil.CodeCritical, "test")
+	if errutil.Stack(err) != "" {
+		t.Error("StackNever: stack is captured")
+	}
+	if errutil.Code(err) != errutil.CodeCritical {
+		t.Errorf("Code() = %q", errutil.Code(err))
+	}
+
+	f = errutil.NewFactory(errutil.Config{StackPolicy: func(code string) bool { return code != errutil.CodeUser }})
+	if errutil.Stack(f.NewWithCode(errutil.CodeUser, "test")) != "" {
+		t.Error("policy: stack is captured for USER")
+	}
+	if stackEnabled && errutil.Stack(f.WithStack(errutil.NewWithCode(errutil.CodeCritical))) == "" {
+		t.Error("policy: stack is not captured for CRITICAL")
+	}
+}
+
+func TestFactoryMaxStackTraceDepth(t *testing.T) {
+	requireStack(t)
+
+	f := errutil.NewFactory(errutil.Config{MaxStackTraceDepth: 1})
+
+	err := f.New("test")
+	if !errutil.StackTruncated(err) {
+		t.Error("StackTruncated() = false, want true")
+	}
+	if errutil.StackTruncated(errutil.New("test")) {
+		t.Error("package New(): StackTruncated() = true")
+	}
+}
+
+func TestFactoryRenderer(t *testing.T) {
+	f := errutil.NewFactory(errutil.Config{
+		Renderer: func(err error) string {
+			return "lib: " + errutil.DefaultRenderer(err)
+		},
+	})
+
+	err := errutil.WithMessage(f.New("dev"), "msg")
+	if err.Error() != "lib: [CRITICAL] dev (msg)" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	defer errutil.SetDefault(nil)
+
+	errutil.SetDefault(errutil.NewFactory(errutil.Config{DefaultCode: "APP"}))
+	if code := errutil.Code(errutil.New("test")); code != "APP" {
+		t.Errorf("Code() = %q, want %q", code, "APP")
+	}
+	if code := errutil.Code(errors.New("foreign")); code != "APP" {
+		t.Errorf("foreign Code() = %q, want %q", code, "APP")
+	}
+
+	errutil.SetDefault(nil)
+	if code := errutil.Code(errutil.New("test")); code != errutil.DefaultCode {
+		t.Errorf("Code() after reset = %q, want %q", code, errutil.DefaultCode)
+	}
+}
+
+func TestSetDefaultConcurrent(t *testing.T) {
+	defer errutil.SetDefault(nil)
+
+	wg := sync.WaitGroup{}
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errutil.SetDefault(errutil.NewFactory(errutil.Config{DefaultCode: "APP", MaxStackTraceDepth: i + 1}))
+		}()
+		go func() {
+			defer wg.Done()
+			if err := errutil.New("test"); !strings.Contains(err.Error(), "test") {
+				t.Errorf("Error() = %q", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
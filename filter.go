@@ -0,0 +1,216 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Классификация фреймов стека (стандартная библиотека, сторонний код, приложение)
+// и настраиваемые фильтры фреймов
+
+package errutil
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// FrameKind - происхождение кода фрейма стека
+type FrameKind int
+
+const (
+	FrameUnknown FrameKind = iota
+	FrameStdlib
+	FrameThirdParty
+	FrameApp
+)
+
+// String - получение текстового представления происхождения фрейма
+func (k FrameKind) String() string {
+	switch k {
+	case FrameStdlib:
+		return "stdlib"
+	case FrameThirdParty:
+		return "third_party"
+	case FrameApp:
+		return "app"
+	}
+
+	return "unknown"
+}
+
+// MarshalText - сериализация происхождения фрейма в текст
+func (k FrameKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText - восстановление происхождения фрейма из текста
+func (k *FrameKind) UnmarshalText(text []byte) error {
+	for _, kind := range []FrameKind{FrameUnknown, FrameStdlib, FrameThirdParty, FrameApp} {
+		if kind.String() == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+
+	return fmt.Errorf("errutil: unknown frame kind %q", text)
+}
+
+// FrameFilter - настройки классификации и фильтрации фреймов стека.
+//
+// Шаблоны пакетов задаются как точное имя пакета, шаблон path.Match
+// или префикс с суффиксом "/..." (например, "github.com/company/app/...").
+type FrameFilter struct {
+	// Пакеты приложения. Если не заданы, используется Config.StackTraceAppPrefix,
+	// а при его отсутствии - основной модуль из debug.ReadBuildInfo.
+	App []string
+
+	// Пакеты, фреймы которых сохраняются, даже если попадают под Exclude
+	Include []string
+
+	// Пакеты, фреймы которых исключаются из стека.
+	// Если не заданы, исключаются runtime и testing.
+	Exclude []string
+
+	// Пакеты-обёртки над errutil, фреймы которых отбрасываются
+	// с конца стека, чтобы стек заканчивался в месте их вызова
+	Helpers []string
+}
+
+// defaultExclude - пакеты, исключаемые из стека по умолчанию
+var defaultExclude = []string{"runtime", "testing"}
+
+// frameFilter - текущие настройки фильтрации фреймов
+var frameFilter atomic.Pointer[FrameFilter]
+
+// SetFrameFilter - установка настроек классификации и фильтрации фреймов стека.
+// Применяется к стекам, фреймы которых ещё не были построены.
+func SetFrameFilter(filter FrameFilter) {
+	frameFilter.Store(&filter)
+}
+
+// currentFrameFilter - получение текущих настроек фильтрации фреймов
+func currentFrameFilter() *FrameFilter {
+	if filter := frameFilter.Load(); filter != nil {
+		return filter
+	}
+
+	return &FrameFilter{}
+}
+
+// mainModule - путь основного модуля приложения из информации о сборке
+var mainModule = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	return info.Main.Path
+})
+
+// isAppPackage - проверка принадлежности пакета приложению.
+// appPrefix - префикс модуля приложения из Config.StackTraceAppPrefix экземпляра, создавшего стек.
+func (f *FrameFilter) isAppPackage(pkg string, appPrefix string) bool {
+	if len(f.App) > 0 {
+		return matchPackages(f.App, pkg)
+	}
+
+	if appPrefix != "" {
+		return strings.HasPrefix(pkg, appPrefix)
+	}
+
+	// Фреймы пакета main содержат имя "main", а не путь основного модуля
+	if module := mainModule(); module != "" {
+		return pkg == "main" || pkg == module || strings.HasPrefix(pkg, module+"/") || pkg == module+"_test"
+	}
+
+	return pkg == "main"
+}
+
+// isExcluded - проверка исключения фреймов пакета из стека
+func (f *FrameFilter) isExcluded(pkg string) bool {
+	if matchPackages(f.Include, pkg) {
+		return false
+	}
+
+	if f.Exclude == nil {
+		return slices.Contains(defaultExclude, pkg)
+	}
+
+	return matchPackages(f.Exclude, pkg)
+}
+
+// isHelper - проверка принадлежности фрейма обёрткам над errutil:
+// по пакету из Helpers или по отметке функции через Helper
+func (f *FrameFilter) isHelper(frame *StackFrame) bool {
+	if matchPackages(f.Helpers, frame.Package) {
+		return true
+	}
+
+	_, ok := helperFuncs.Load(frame.Package + "." + frame.Function)
+
+	return ok
+}
+
+// helperFuncs - полные имена функций, отмеченных через Helper
+var helperFuncs sync.Map
+
+// Helper - отметка вызывающей функции как вспомогательной, аналогично testing.T.Helper.
+// Фреймы отмеченных функций отбрасываются с конца стека, поэтому стек ошибки,
+// созданной внутри такой функции, заканчивается в месте её вызова.
+func Helper() {
+	var pc [1]uintptr
+	if runtime.Callers(2, pc[:]) == 0 {
+		return
+	}
+
+	frame, _ := runtime.CallersFrames(pc[:]).Next()
+	pkg, function := splitPackageAndFunction(frame.Function)
+	name := pkg + "." + function
+
+	if _, ok := helperFuncs.Load(name); !ok {
+		helperFuncs.Store(name, struct{}{})
+	}
+}
+
+// matchPackages - проверка соответствия пакета хотя бы одному из шаблонов
+func matchPackages(patterns []string, pkg string) bool {
+	for _, pattern := range patterns {
+		if matchPackage(pattern, pkg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPackage - проверка соответствия пакета шаблону
+func matchPackage(pattern string, pkg string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+
+	ok, _ := path.Match(pattern, pkg)
+
+	return ok
+}
+
+// isStdlibPackage - проверка принадлежности пакета стандартной библиотеке по расположению файла в GOROOT.
+// Для путей, сокращённых при сборке с -trimpath, пакетом стандартной библиотеки считается пакет
+// без точки в первом элементе пути.
+func isStdlibPackage(pkg string, file string) bool {
+	if goRoot != "" && strings.HasPrefix(file, goRoot+"/") {
+		return true
+	}
+
+	if pkg == "" || pkg == "main" || path.IsAbs(file) || filepath.IsAbs(file) {
+		return false
+	}
+
+	first, _, _ := strings.Cut(pkg, "/")
+
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,133 @@
+package errutil_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestFrameKind(t *testing.T) {
+	requireStack(t)
+
+	frames := errutil.StackTrace(errutil.New("test"))
+	if len(frames) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+
+	last := frames[len(frames)-1]
+	if !last.InApp || last.Kind != errutil.FrameApp {
+		t.Errorf("test frame InApp = %v, Kind = %v", last.InApp, last.Kind)
+	}
+
+	data, err := json.Marshal(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded errutil.StackFrame
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded.Kind != errutil.FrameApp {
+		t.Errorf("decoded Kind = %v, %v (%s)", decoded.Kind, err, data)
+	}
+}
+
+func TestFrameFilter(t *testing.T) {
+	requireStack(t)
+
+	defer errutil.SetFrameFilter(errutil.FrameFilter{})
+
+	errutil.SetFrameFilter(errutil.FrameFilter{
+		Include: []string{"testing"},
+		Helpers: []string{"github.com/kontora13-go/*_test"},
+	})
+
+	frames := errutil.StackTrace(errutil.New("test"))
+	if len(frames) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+	last := frames[len(frames)-1]
+	if last.Package != "testing" || last.Kind != errutil.FrameStdlib || last.InApp {
+		t.Errorf("innermost frame = %+v, want testing frame", last)
+	}
+
+	errutil.SetFrameFilter(errutil.FrameFilter{
+		App:     []string{"example.com/app/..."},
+		Exclude: []string{"runtime", "testing"},
+	})
+
+	frames = errutil.StackTrace(errutil.New("test"))
+	if len(frames) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+	last = frames[len(frames)-1]
+	if last.InApp || last.Kind != errutil.FrameThirdParty {
+		t.Errorf("test frame InApp = %v, Kind = %v", last.InApp, last.Kind)
+	}
+}
+
+func TestFrameKindAppFirst(t *testing.T) {
+	requireStack(t)
+
+	defer errutil.SetFrameFilter(errutil.FrameFilter{})
+
+	// Пакет приложения без точки в пути (module myapp) не считается стандартной библиотекой
+	errutil.SetFrameFilter(errutil.FrameFilter{
+		App:     []string{"testing", "github.com/kontora13-go/errutil_test"},
+		Include: []string{"testing"},
+	})
+
+	frames := errutil.StackTrace(errutil.New("test"))
+	if len(frames) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+	for _, frame := range frames {
+		if frame.Package == "testing" && (frame.Kind != errutil.FrameApp || !frame.InApp) {
+			t.Errorf("frame %s.%s Kind = %v, InApp = %v", frame.Package, frame.Function, frame.Kind, frame.InApp)
+		}
+	}
+}
+
+func notFound(id int) error {
+	errutil.Helper()
+	return errutil.NewWithCodef("NOT_FOUND", "order %d not found", id)
+}
+
+func notFoundWrapped(id int) error {
+	errutil.Helper()
+	return errutil.WithMessage(notFound(id), "Заказ не найден")
+}
+
+func newSkipped() error {
+	return errutil.NewSkip(1, "skipped")
+}
+
+func TestHelper(t *testing.T) {
+	requireStack(t)
+
+	line := currentLine() + 1
+	err := notFoundWrapped(42)
+
+	frames := errutil.StackTrace(err)
+	if len(frames) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+	if last := frames[len(frames)-1]; last.Function != "TestHelper" || last.LineNumber != line {
+		t.Errorf("innermost frame = %s:%d, want TestHelper:%d", last.Function, last.LineNumber, line)
+	}
+
+	line = currentLine() + 1
+	err = newSkipped()
+
+	frames = errutil.StackTrace(err)
+	if len(frames) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+	if last := frames[len(frames)-1]; last.Function != "TestHelper" || last.LineNumber != line {
+		t.Errorf("innermost frame = %s:%d, want TestHelper:%d", last.Function, last.LineNumber, line)
+	}
+
+	line = currentLine() + 1
+	err = errutil.WithStackSkip(nil, 0)
+	if last := errutil.StackTrace(err); len(last) == 0 || last[len(last)-1].LineNumber != line {
+		t.Errorf("WithStackSkip(0) innermost frame = %v, want line %d", last, line)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Поддержка интерфейса fmt.Formatter для ошибок пакета
+
+package errutil
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const formatIndent = "    "
+
+// formatError - реализация fmt.Formatter для всех врапперов ошибок.
+// %v, %s и %q выводят компактное представление errorString,
+// %+v - полную цепочку с кодами, сообщениями и стеком вызовов.
+func formatError(s fmt.State, verb rune, err error) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = io.WriteString(s, verboseString(err))
+			return
+		}
+		_, _ = io.WriteString(s, err.Error())
+	case 's':
+		_, _ = io.WriteString(s, err.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", err.Error())
+	default:
+		_, _ = fmt.Fprintf(s, "%%!%c(errutil=%s)", verb, err.Error())
+	}
+}
+
+// verboseString - построение подробного представления цепочки ошибок
+func verboseString(err error) string {
+	buf := strings.Builder{}
+
+	buf.WriteString(err.Error())
+	buf.WriteString("\n")
+	writeVerbose(&buf, err)
+
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// writeVerbose - вывод всех уровней цепочки ошибок, начиная с внешнего.
+// Ошибки мультиошибки выводятся по порядку с отступом.
+func writeVerbose(buf *strings.Builder, err error) {
+	// Базовый отступ и количество выведенных веток каждой мультиошибки
+	type multiLevel struct {
+		indent int
+		n      int
+	}
+
+	var multis []multiLevel
+	indent := 0
+	index := causerIndex{err: err}
+	skipBranch := false
+
+	walk(err, func(l *layer) walkAction {
+		if skipBranch {
+			skipBranch = false
+			return walkSkip
+		}
+
+		out := strings.Builder{}
+		action := walkNext
+
+		switch {
+		case l.truncated != truncationNone:
+			_, _ = fmt.Fprintf(&out, "truncated: %s\n", l.truncated)
+		case l.seg != nil:
+			e := l.node()
+			writeSegment(&out, l.seg, e.segs[:l.index], e.root())
+		case len(l.multi) > 0:
+			out.WriteString("errors:\n")
+		case l.next != nil && index.contains(l.next):
+			_, _ = fmt.Fprintf(&out, "wrapped: %s\n", l.err.Error())
+		default:
+			_, _ = fmt.Fprintf(&out, "cause: %s\n", l.err.Error())
+			action = walkSkip
+		}
+
+		writeIndented(buf, out.String(), indent)
+
+		return action
+	}, func(e walkEvent, branch error) {
+		switch e {
+		case walkMultiStart:
+			multis = append(multis, multiLevel{indent: indent})
+		case walkBranchStart:
+			m := &multis[len(multis)-1]
+			m.n++
+
+			// Сторонние ошибки выводятся своим подробным представлением
+			text := branch.Error()
+			switch branch.(type) {
+			case *node, *multiError:
+			default:
+				text = fmt.Sprintf("%+v", branch)
+				skipBranch = true
+			}
+
+			first, rest, _ := strings.Cut(text, "\n")
+			_, _ = fmt.Fprintf(buf, "%s#%d: %s\n", strings.Repeat(formatIndent, m.indent+1), m.n, first)
+			writeIndented(buf, rest, m.indent+2)
+			indent = m.indent + 2
+		case walkMultiEnd:
+			indent = multis[len(multis)-1].indent
+			multis = multis[:len(multis)-1]
+		}
+	})
+}
+
+// writeSegment - вывод сегмента ошибки, inner и root - внутренние сегменты и исходная ошибка
+func writeSegment(buf *strings.Builder, s *segment, inner []*segment, root error) {
+	switch s.kind {
+	case segmentCode:
+		_, _ = fmt.Fprintf(buf, "code: %s\n", s.code)
+	case segmentStack:
+		if s.code != "" {
+			_, _ = fmt.Fprintf(buf, "code: %s\n", s.code)
+		}
+		writeStack(buf, s.stack, innerSegmentStack(inner, root))
+	case segmentMessage:
+		_, _ = fmt.Fprintf(buf, "message: %s\n", s.msg)
+	case segmentDev:
+		_, _ = fmt.Fprintf(buf, "dev: %s\n", s.devMessage())
+	case segmentFields:
+		writeFields(buf, s.fields)
+	}
+}
+
+// writeStack - вывод стека вызовов в формате runtime/debug.Stack().
+// Если в цепочке ниже есть другой стек inner, выводятся только фреймы,
+// которыми внешний стек отличается от него, с пометкой "wrapped at".
+func writeStack(buf *strings.Builder, s *stack, inner *stack) {
+	frames := s.Frames()
+	if len(frames) == 0 {
+		return
+	}
+
+	if inner == nil {
+		buf.WriteString("stack:\n")
+		writeIndented(buf, s.String(), 1)
+		return
+	}
+
+	innerFrames := inner.Frames()
+	common := 0
+	for common < len(frames) && common < len(innerFrames) && sameFrame(&frames[common], &innerFrames[common]) {
+		common++
+	}
+	if common == len(frames) {
+		return
+	}
+
+	buf.WriteString("wrapped at:\n")
+	for _, frame := range frames[common:] {
+		writeIndented(buf, frame.String(), 1)
+	}
+}
+
+// innerStack - поиск ближайшего непустого стека вызовов в цепочке ошибок без учёта мультиошибок
+func innerStack(err error) *stack {
+	var inner *stack
+
+	walk(err, func(l *layer) walkAction {
+		if l.seg != nil && l.seg.kind == segmentStack && len(l.seg.stack.Frames()) > 0 {
+			inner = l.seg.stack
+			return walkStop
+		}
+		if l.multi != nil {
+			return walkStop
+		}
+
+		return walkNext
+	}, nil)
+
+	return inner
+}
+
+// innerSegmentStack - поиск ближайшего непустого стека вызовов в сегментах segs
+// (от внешнего к внутреннему) и далее в исходной ошибке root
+func innerSegmentStack(segs []*segment, root error) *stack {
+	for i := len(segs) - 1; i >= 0; i-- {
+		if s := segs[i]; s.kind == segmentStack && len(s.stack.Frames()) > 0 {
+			return s.stack
+		}
+	}
+
+	return innerStack(root)
+}
+
+// sameFrame - проверка совпадения фреймов двух стеков
+func sameFrame(a *StackFrame, b *StackFrame) bool {
+	return a.PC == b.PC && a.Function == b.Function && a.LineNumber == b.LineNumber
+}
+
+// writeFields - вывод полей ошибки в порядке сортировки ключей
+func writeFields(buf *strings.Builder, fields map[string]any) {
+	buf.WriteString("fields:")
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		_, _ = fmt.Fprintf(buf, " %s=%v", key, fields[key])
+	}
+	buf.WriteString("\n")
+}
+
+// writeIndented - вывод многострочного текста со сдвигом на depth отступов
+func writeIndented(buf *strings.Builder, text string, depth int) {
+	indent := strings.Repeat(formatIndent, depth)
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		buf.WriteString(indent)
+		buf.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			buf.WriteString("\n")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package errutil_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestFormat(t *testing.T) {
+	err := errutil.WithCode(fmt.Errorf("first err"), "INTERNAL")
+	err = errutil.WithStack(err)
+	err = errutil.WithMessage(err, "user message")
+	err = errutil.WithDevMessage(err, "dev 1", "dev 2")
+
+	want := "[INTERNAL] dev 1: dev 2, first err (user message)"
+	if got := fmt.Sprintf("%v", err); got != want {
+		t.Errorf("%%v = %q, want %q", got, want)
+	}
+	if got := fmt.Sprintf("%s", err); got != want {
+		t.Errorf("%%s = %q, want %q", got, want)
+	}
+	if got := fmt.Sprintf("%q", err); got != fmt.Sprintf("%q", want) {
+		t.Errorf("%%q = %s", got)
+	}
+
+	verbose := fmt.Sprintf("%+v", err)
+	for _, part := range []string{
+		want + "\n",
+		"dev: dev 1: dev 2\n",
+		"message: user message\n",
+		"code: INTERNAL\n",
+		"stack:\n",
+		"format_test.go:",
+		"cause: first err",
+	} {
+		if !stackEnabled && (part == "stack:\n" || part == "format_test.go:") {
+			continue
+		}
+		if !strings.Contains(verbose, part) {
+			t.Errorf("%%+v does not contain %q:\n%s", part, verbose)
+		}
+	}
+}
+
+func repoFind() error {
+	return errutil.NewWithCode("NOT_FOUND", "order not found")
+}
+
+func serviceFind() error {
+	return errutil.WithDevMessage(repoFind(), "find order")
+}
+
+func handlerFind(line *int) error {
+	err := serviceFind()
+	*line = currentLine() + 1
+	return errutil.WithStack(err)
+}
+
+func TestFormatWrappedAt(t *testing.T) {
+	requireStack(t)
+
+	var line int
+	err := handlerFind(&line)
+
+	traces := errutil.StackTraces(err)
+	if len(traces) != 2 {
+		t.Fatalf("StackTraces() has %d traces, want 2", len(traces))
+	}
+	if outer := traces[0]; outer[len(outer)-1].Function != "handlerFind" || outer[len(outer)-1].LineNumber != line {
+		t.Errorf("outer trace ends at %+v", outer[len(outer)-1])
+	}
+	if inner := traces[1]; inner[len(inner)-1].Function != "repoFind" {
+		t.Errorf("inner trace ends at %+v", inner[len(inner)-1])
+	}
+
+	verbose := fmt.Sprintf("%+v", err)
+	if strings.Count(verbose, "stack:\n") != 1 || strings.Count(verbose, "wrapped at:\n") != 1 {
+		t.Fatalf("%%+v must contain one stack and one wrapped at:\n%s", verbose)
+	}
+
+	_, wrapped, _ := strings.Cut(verbose, "wrapped at:\n")
+	wrapped, _, _ = strings.Cut(wrapped, "code:")
+	if strings.Count(wrapped, "format_test.go:") != 1 || !strings.Contains(wrapped, fmt.Sprintf("format_test.go:%d ", line)) {
+		t.Errorf("wrapped at must contain only handler frame at line %d:\n%s", line, wrapped)
+	}
+}
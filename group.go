@@ -0,0 +1,116 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Группа горутин, сохраняющая все ошибки, стеки вызовов и паники
+
+package errutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Group - группа горутин с API, аналогичным golang.org/x/sync/errgroup.
+// В отличие от errgroup сохраняет ошибки всех горутин, преобразует паники
+// в ошибки с кодом CodePanic и добавляет к каждой ошибке стек места вызова Go.
+// Нулевое значение готово к использованию и не отменяет работу при ошибке.
+type Group struct {
+	cancel func(error)
+
+	wg   sync.WaitGroup
+	sem  chan struct{}
+	errs Collector
+}
+
+// WithContext - создание группы с производным контекстом,
+// который отменяется при первой ошибке в группе или по завершении Wait
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go - запуск функции в новой горутине.
+// Если достигнут лимит горутин, вызов блокируется до освобождения места.
+func (g *Group) Go(fn func() error) {
+	callSite := Default().captureStack(1, "")
+
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	g.run(fn, callSite)
+}
+
+// TryGo - запуск функции в новой горутине, только если не достигнут лимит горутин.
+// Возвращает признак запуска.
+func (g *Group) TryGo(fn func() error) bool {
+	callSite := Default().captureStack(1, "")
+
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+
+	g.run(fn, callSite)
+
+	return true
+}
+
+// SetLimit - ограничение числа одновременно работающих горутин, отрицательное значение снимает ограничение.
+// Лимит нельзя изменять, пока в группе есть работающие горутины.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("errutil: modify limit while %v goroutines in the group are still active", len(g.sem)))
+	}
+
+	g.sem = make(chan struct{}, n)
+}
+
+// Wait - ожидание завершения всех горутин группы.
+// Возвращает все ошибки горутин, объединённые через Join, или nil.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+
+	err := g.errs.Err()
+	if g.cancel != nil {
+		g.cancel(err)
+	}
+
+	return err
+}
+
+// run - выполнение функции в горутине с сохранением ошибки
+func (g *Group) run(fn func() error, callSite *stack) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.done()
+
+		if err := Safe(fn); err != nil {
+			err = newNode(err, segment{kind: segmentStack, stack: callSite})
+
+			g.errs.Add(err)
+			if g.cancel != nil {
+				g.cancel(err)
+			}
+		}
+	}()
+}
+
+// done - завершение горутины и освобождение места в лимите
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
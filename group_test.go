@@ -0,0 +1,93 @@
+package errutil_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestGroup(t *testing.T) {
+	g := errutil.Group{}
+	g.SetLimit(4)
+
+	_, _, line, _ := runtime.Caller(0)
+	for i := 0; i < 10; i++ {
+		g.Go(func() error {
+			switch i {
+			case 3:
+				panic("boom")
+			case 5, 7:
+				return errutil.Newf("err%v", i)
+			}
+			return nil
+		})
+	}
+
+	err := g.Wait()
+
+	var multi interface{ Unwrap() []error }
+	if !errors.As(err, &multi) || len(multi.Unwrap()) != 3 {
+		t.Fatalf("Wait() = %v, want 3 errors", err)
+	}
+	if got := errutil.Code(err); got != errutil.CodePanic {
+		t.Errorf("Code() = %q, want %q", got, errutil.CodePanic)
+	}
+
+	for _, e := range multi.Unwrap() {
+		frames := errutil.StackTrace(e)
+		if !stackEnabled {
+			continue
+		}
+		if len(frames) == 0 || frames[len(frames)-1].LineNumber != line+2 {
+			t.Errorf("StackTrace() of %v does not point to Go call site at line %d", e, line+2)
+		}
+	}
+
+	if text := err.Error(); !strings.Contains(text, "err5") || !strings.Contains(text, "err7") ||
+		!strings.Contains(text, "boom") {
+		t.Errorf("Error() = %q", text)
+	}
+}
+
+func TestGroupWithContext(t *testing.T) {
+	g, ctx := errutil.WithContext(context.Background())
+
+	g.Go(func() error {
+		return errutil.New("first")
+	})
+	g.Go(func() error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := g.Wait()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() = %v, want context.Canceled", err)
+	}
+	if cause := context.Cause(ctx); errutil.DevMessage(cause) != "first" {
+		t.Errorf("context.Cause() = %v", cause)
+	}
+
+}
+
+func TestGroupTryGo(t *testing.T) {
+	g := errutil.Group{}
+	g.SetLimit(1)
+
+	release := make(chan struct{})
+	if !g.TryGo(func() error { <-release; return nil }) {
+		t.Error("TryGo() with free slot = false")
+	}
+	if g.TryGo(func() error { return nil }) {
+		t.Error("TryGo() over limit = true")
+	}
+	close(release)
+
+	if err := g.Wait(); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Перебор цепочки ошибок итератором и поиск ошибок в цепочке
+
+package errutil
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+)
+
+// Chain - итератор по всем ошибкам цепочки от внешней к внутренней.
+// Переходы выполняются через Cause() error, Unwrap() error и Unwrap() []error,
+// ошибки мультиошибки перебираются по порядку вслед за ней самой.
+// Каждый уровень ошибки пакета (код, стек, сообщение) перебирается как отдельная ошибка,
+// реализующая только интерфейс своего уровня (Coder, Tracer, Messager, DevMessager, Fielder).
+// Циклы и уровни глубже Config.MaxChainDepth не перебираются.
+func Chain(err error) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		walk(err, func(l *layer) walkAction {
+			if l.truncated != truncationNone {
+				return walkNext
+			}
+
+			e := l.err
+			if n := l.node(); n != nil {
+				e = newView(n.prefix(l.index+1), l.seg)
+			}
+			if !yield(e) {
+				return walkStop
+			}
+
+			return walkNext
+		}, nil)
+	}
+}
+
+// Find - поиск первой ошибки цепочки типа T.
+// В отличие от errors.As, T может быть любым интерфейсом, в том числе Coder, Tracer и другими.
+func Find[T any](err error) (T, bool) {
+	for e := range Chain(err) {
+		if t, ok := e.(T); ok {
+			return t, true
+		}
+	}
+
+	var zero T
+
+	return zero, false
+}
+
+// FindAll - поиск всех ошибок цепочки типа T
+func FindAll[T any](err error) []T {
+	var found []T
+	for e := range Chain(err) {
+		if t, ok := e.(T); ok {
+			found = append(found, t)
+		}
+	}
+
+	return found
+}
+
+// Has - проверка наличия в цепочке ошибки, удовлетворяющей условию match
+func Has(err error, match func(err error) bool) bool {
+	for e := range Chain(err) {
+		if match(e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+----------
+*/
+
+// viewer - уровень ошибки пакета, перебираемый Chain
+type viewer interface {
+	viewNode() *node
+}
+
+// view - уровень ошибки пакета без собственных интерфейсов:
+// текст, сравнение и распаковка совпадают с ошибкой из сегментов уровня и внутренних
+type view struct {
+	e *node
+}
+
+// newView - создание уровня для ошибки e с внешним сегментом seg
+func newView(e *node, seg *segment) error {
+	v := view{e: e}
+
+	switch seg.kind {
+	case segmentCode:
+		return &codeView{view: v}
+	case segmentStack:
+		switch {
+		case seg.stack != nil && seg.code != "":
+			return &codeStackView{stackView: stackView{view: v}}
+		case seg.stack != nil:
+			return &stackView{view: v}
+		case seg.code != "":
+			return &codeView{view: v}
+		}
+	case segmentMessage:
+		return &messageView{view: v}
+	case segmentDev:
+		return &devView{view: v}
+	case segmentFields:
+		return &fieldsView{view: v}
+	}
+
+	return &v
+}
+
+func (v *view) viewNode() *node {
+	return v.e
+}
+
+// Error - получение текстового представления ошибки
+func (v *view) Error() string {
+	return v.e.Error()
+}
+
+// Format - форматирование ошибки для пакета fmt
+func (v *view) Format(s fmt.State, verb rune) {
+	formatError(s, verb, v.e)
+}
+
+// Unwrap - распаковка ошибки внутреннего уровня
+func (v *view) Unwrap() error {
+	return v.e.Unwrap()
+}
+
+// Is - сравнение с ошибкой для errors.Is
+func (v *view) Is(target error) bool {
+	return v.e.Is(target)
+}
+
+// codeView - уровень кода
+type codeView struct {
+	view
+}
+
+// Code - получение кода уровня
+func (v *codeView) Code() string {
+	return v.e.top().code
+}
+
+// stackView - уровень стека вызовов
+type stackView struct {
+	view
+}
+
+// Stack - получение стека вызовов уровня
+func (v *stackView) Stack() string {
+	return v.e.top().stack.String()
+}
+
+// StackTrace - получение фреймов стека уровня
+func (v *stackView) StackTrace() []StackFrame {
+	return v.e.top().stack.Frames()
+}
+
+// StackTruncated - признак того, что стек уровня обрезан по максимальной глубине
+func (v *stackView) StackTruncated() bool {
+	return v.e.top().stack.Truncated()
+}
+
+// codeStackView - уровень ошибки, созданной с кодом и стеком вызовов
+type codeStackView struct {
+	stackView
+}
+
+// Code - получение кода уровня
+func (v *codeStackView) Code() string {
+	return v.e.top().code
+}
+
+// messageView - уровень сообщения для пользователя
+type messageView struct {
+	view
+}
+
+// Message - получение сообщения уровня
+func (v *messageView) Message() string {
+	return v.e.top().msg
+}
+
+// devView - уровень сообщений для разработчика
+type devView struct {
+	view
+}
+
+// DevMessage - получение сообщений уровня одной строкой
+func (v *devView) DevMessage() string {
+	return v.e.top().devMessage()
+}
+
+// DevMessages - получение списка сообщений уровня
+func (v *devView) DevMessages() []string {
+	return slices.Clone(v.e.top().dev)
+}
+
+// fieldsView - уровень полей контекста
+type fieldsView struct {
+	view
+}
+
+// Fields - получение полей контекста уровня
+func (v *fieldsView) Fields() map[string]any {
+	return maps.Clone(v.e.top().fields)
+}
@@ -0,0 +1,110 @@
+package errutil_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestChain(t *testing.T) {
+	root := errors.New("root")
+	err := errutil.WithMessage(errutil.WithCode(fmt.Errorf("wrap: %w", root), errutil.CodeUser), "msg")
+
+	var got []error
+	for e := range errutil.Chain(err) {
+		got = append(got, e)
+	}
+	if len(got) != 4 {
+		t.Fatalf("Chain() = %d errors, want 4: %v", len(got), got)
+	}
+	if m, ok := got[0].(errutil.Messager); !ok || m.Message() != "msg" || got[0].Error() != err.Error() {
+		t.Errorf("Chain()[0] = %v, want message layer", got[0])
+	}
+	if _, ok := got[0].(errutil.Coder); ok {
+		t.Error("message layer implements Coder")
+	}
+	if !errors.Is(err, got[0]) || !errors.Is(got[0], root) {
+		t.Error("errors.Is() = false for message layer")
+	}
+	if errutil.Code(got[1]) != errutil.CodeUser || errutil.Message(got[1]) != "" {
+		t.Errorf("Chain()[1] = %v, want code layer", got[1])
+	}
+	if _, ok := got[1].(errutil.Messager); ok {
+		t.Error("code layer implements Messager")
+	}
+	if got[3] != root {
+		t.Errorf("Chain()[3] = %v, want root", got[3])
+	}
+
+	// Прерывание перебора
+	n := 0
+	for range errutil.Chain(err) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("break: %d iterations", n)
+	}
+}
+
+func TestChainMulti(t *testing.T) {
+	err := errors.Join(errutil.New("first"), errutil.WithCode(io.EOF, errutil.CodeUser))
+
+	// Мультиошибка, уровни первой ошибки (сообщение, стек), уровень кода второй и io.EOF
+	var texts []string
+	for e := range errutil.Chain(err) {
+		texts = append(texts, e.Error())
+	}
+	if len(texts) != 5 || texts[4] != io.EOF.Error() {
+		t.Errorf("Chain() = %q", texts)
+	}
+}
+
+func TestFindAllTracer(t *testing.T) {
+	err := errutil.WithMessage(errutil.WithStack(errutil.WithDevMessage(errutil.New("x"), "dev")), "msg")
+
+	want := 0
+	if stackEnabled {
+		want = 2
+	}
+	if tracers := errutil.FindAll[errutil.Tracer](err); len(tracers) != want {
+		t.Errorf("FindAll[Tracer]() = %d, want %d", len(tracers), want)
+	}
+	if messagers := errutil.FindAll[errutil.Messager](err); len(messagers) != 1 {
+		t.Errorf("FindAll[Messager]() = %d, want 1", len(messagers))
+	}
+	if coders := errutil.FindAll[errutil.Coder](err); len(coders) != 1 || coders[0].Code() != errutil.DefaultCode {
+		t.Errorf("FindAll[Coder]() = %v", coders)
+	}
+}
+
+func TestFind(t *testing.T) {
+	pathErr := &os.PathError{Op: "open", Path: "a.txt", Err: os.ErrNotExist}
+	err := errutil.WithDevMessage(errutil.WithStack(pathErr), "load")
+
+	if found, ok := errutil.Find[*os.PathError](err); !ok || found != pathErr {
+		t.Errorf("Find[*os.PathError]() = %v, %v", found, ok)
+	}
+	if tracer, ok := errutil.Find[errutil.Tracer](err); ok != stackEnabled || ok && tracer.Stack() == "" {
+		t.Errorf("Find[Tracer]() = %v, %v", tracer, ok)
+	}
+	if _, ok := errutil.Find[*errutil.PanicError](err); ok {
+		t.Error("Find[*PanicError]() found")
+	}
+
+	multi := errutil.Join(errutil.WithCode(pathErr, errutil.CodeUser), &os.PathError{Op: "read", Err: io.EOF})
+	if all := errutil.FindAll[*os.PathError](multi); len(all) != 2 || all[0] != pathErr {
+		t.Errorf("FindAll[*os.PathError]() = %v", all)
+	}
+
+	if !errutil.Has(multi, func(err error) bool { return errors.Is(err, io.EOF) }) {
+		t.Error("Has(io.EOF) = false")
+	}
+	if errutil.Has(err, func(err error) bool { return errutil.Code(err) == errutil.CodeUser }) {
+		t.Error("Has(CodeUser) = true")
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Сериализация цепочки ошибок в JSON и её восстановление без потерь
+
+package errutil
+
+import (
+	"encoding/json"
+)
+
+const (
+	jsonKindCode    = "code"
+	jsonKindStack   = "stack"
+	jsonKindMessage = "message"
+	jsonKindDev     = "dev"
+	jsonKindFields  = "fields"
+	jsonKindMulti   = "multi"
+	jsonKindForeign = "foreign"
+
+	// Обрыв цепочки при обходе (цикл, превышение Config.MaxChainDepth)
+	jsonKindTruncated = "truncated"
+)
+
+// jsonNode - JSON-представление одного уровня цепочки ошибок
+type jsonNode struct {
+	Kind    string         `json:"kind"`
+	Code    string         `json:"code,omitempty"`
+	Default bool           `json:"default_code,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Dev     []string       `json:"dev,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Stack   []StackFrame   `json:"stack,omitempty"`
+	Elided  bool           `json:"truncated,omitempty"`
+	Text    string         `json:"text,omitempty"`
+	Errors  []*jsonNode    `json:"errors,omitempty"`
+	Cause   *jsonNode      `json:"cause,omitempty"`
+}
+
+// JSONError - контейнер для сериализации ошибки в JSON и обратно,
+// например, в качестве поля структуры ответа
+type JSONError struct {
+	Err error
+}
+
+// MarshalJSON - сериализация цепочки ошибок
+func (e JSONError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeError(e.Err))
+}
+
+// UnmarshalJSON - восстановление цепочки ошибок
+func (e *JSONError) UnmarshalJSON(data []byte) error {
+	var node *jsonNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return err
+	}
+
+	e.Err = decodeError(node)
+
+	return nil
+}
+
+// Decode - восстановление в target ошибки из JSON, полученного при сериализации ошибки errutil.
+// Значения структурированных полей восстанавливаются по правилам encoding/json
+// (например, числа - как float64). Возвращается ошибка разбора JSON, target при этом не изменяется.
+func Decode(data []byte, target *error) error {
+	var e JSONError
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+
+	*target = e.Err
+
+	return nil
+}
+
+// marshalError - реализация json.Marshaler для всех врапперов ошибок
+func marshalError(err error) ([]byte, error) {
+	return json.Marshal(encodeError(err))
+}
+
+// encodeError - построение JSON-представления цепочки ошибок
+func encodeError(err error) *jsonNode {
+	var root, last *jsonNode
+	slot := &root
+
+	// Мультиошибки и ветки, для которых строится представление
+	var multis []*jsonNode
+	var branches []**jsonNode
+	index := causerIndex{err: err}
+
+	walk(err, func(l *layer) walkAction {
+		action := walkNext
+
+		switch {
+		case l.truncated != truncationNone:
+			last = &jsonNode{Kind: jsonKindTruncated, Text: l.truncated.marker()}
+		case l.seg != nil:
+			last = encodeSegment(l.seg)
+		case l.multi != nil && isMultiError(l.err):
+			last = &jsonNode{Kind: jsonKindMulti}
+		default:
+			last = &jsonNode{Kind: jsonKindForeign, Text: l.err.Error()}
+			if !index.contains(l.err) {
+				action = walkSkip
+			}
+		}
+
+		*slot = last
+		slot = &last.Cause
+
+		return action
+	}, func(e walkEvent, _ error) {
+		switch e {
+		case walkMultiStart:
+			multis = append(multis, last)
+		case walkBranchStart:
+			slot = new(*jsonNode)
+			branches = append(branches, slot)
+		case walkBranchEnd:
+			branch := *branches[len(branches)-1]
+			branches = branches[:len(branches)-1]
+			if branch != nil {
+				multi := multis[len(multis)-1]
+				multi.Errors = append(multi.Errors, branch)
+			}
+		case walkMultiEnd:
+			multis = multis[:len(multis)-1]
+		}
+	})
+
+	return root
+}
+
+// isMultiError - проверка, что ошибка является мультиошибкой errutil
+func isMultiError(err error) bool {
+	_, ok := err.(*multiError)
+
+	return ok
+}
+
+// encodeSegment - построение JSON-представления сегмента ошибки
+func encodeSegment(s *segment) *jsonNode {
+	switch s.kind {
+	case segmentCode:
+		return &jsonNode{Kind: jsonKindCode, Code: s.code}
+	case segmentStack:
+		return &jsonNode{Kind: jsonKindStack, Code: s.code, Default: s.implicit, Stack: s.stack.Frames(), Elided: s.stack.Truncated()}
+	case segmentMessage:
+		return &jsonNode{Kind: jsonKindMessage, Message: s.msg}
+	case segmentDev:
+		return &jsonNode{Kind: jsonKindDev, Dev: s.dev}
+	}
+
+	return &jsonNode{Kind: jsonKindFields, Fields: s.fields}
+}
+
+// decodeError - восстановление цепочки ошибок из JSON-представления
+func decodeError(node *jsonNode) error {
+	// Уровни цепочки от внешнего к внутреннему
+	var nodes []*jsonNode
+	for ; node != nil; node = node.Cause {
+		nodes = append(nodes, node)
+	}
+
+	var err error
+	for i := len(nodes) - 1; i >= 0; i-- {
+		err = decodeLayer(nodes[i], err)
+	}
+
+	return err
+}
+
+// decodeLayer - восстановление одного уровня цепочки, оборачивающего cause
+func decodeLayer(node *jsonNode, cause error) error {
+	switch node.Kind {
+	case jsonKindCode:
+		return newNode(cause, segment{kind: segmentCode, code: node.Code})
+	case jsonKindStack:
+		return newNode(cause, segment{kind: segmentStack, code: node.Code, implicit: node.Default, stack: stackFromFrames(node.Stack, node.Elided)})
+	case jsonKindMessage:
+		return newNode(cause, segment{kind: segmentMessage, msg: node.Message})
+	case jsonKindDev:
+		return newNode(cause, segment{kind: segmentDev, dev: node.Dev})
+	case jsonKindFields:
+		return newNode(cause, segment{kind: segmentFields, fields: node.Fields})
+	case jsonKindMulti:
+		return &multiError{errs: decodeErrors(node.Errors)}
+	case jsonKindTruncated:
+		return &errForeign{text: node.Text}
+	}
+
+	if node.Errors != nil {
+		return &errForeignJoin{text: node.Text, errs: decodeErrors(node.Errors)}
+	}
+
+	return &errForeign{text: node.Text, cause: cause}
+}
+
+func decodeErrors(nodes []*jsonNode) []error {
+	errs := make([]error, 0, len(nodes))
+	for _, node := range nodes {
+		if err := decodeError(node); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+/*
+----------
+*/
+
+// errForeign - восстановленная сторонняя ошибка, сохраняющая исходный текст
+type errForeign struct {
+	text  string
+	cause error
+}
+
+// Error - получение текстового представления ошибки
+func (e *errForeign) Error() string {
+	return e.text
+}
+
+// Unwrap - распаковка вложенной ошибки
+func (e *errForeign) Unwrap() error {
+	return e.cause
+}
+
+// errForeignJoin - восстановленная сторонняя мультиошибка, сохраняющая исходный текст
+type errForeignJoin struct {
+	text string
+	errs []error
+}
+
+// Error - получение текстового представления ошибки
+func (e *errForeignJoin) Error() string {
+	return e.text
+}
+
+// Unwrap - получение списка объединённых ошибок
+func (e *errForeignJoin) Unwrap() []error {
+	return e.errs
+}
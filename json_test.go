@@ -0,0 +1,83 @@
+package errutil_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestJSON(t *testing.T) {
+	var err error
+
+	err = fmt.Errorf("first err")
+	err = errutil.WithCode(err, "INTERNAL")
+	err = errutil.WithStack(err)
+	err = errutil.WithDevMessage(err, "test user error", "one")
+	err = errutil.WithMessage(err, "user message")
+	err = fmt.Errorf("handler: %w", err)
+	err = errutil.Join(err, errutil.NewWithCode(errutil.CodeUser, "validation"))
+	err = errutil.WithDevMessage(err, "dev")
+
+	data, e := json.Marshal(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var decoded error
+	if e := errutil.Decode(data, &decoded); e != nil {
+		t.Fatal(e)
+	}
+
+	if decoded.Error() != err.Error() {
+		t.Errorf("Error() = %q, want %q", decoded.Error(), err.Error())
+	}
+	if errutil.Code(decoded) != errutil.Code(err) {
+		t.Errorf("Code() = %q, want %q", errutil.Code(decoded), errutil.Code(err))
+	}
+	if errutil.Message(decoded) != errutil.Message(err) {
+		t.Errorf("Message() = %q, want %q", errutil.Message(decoded), errutil.Message(err))
+	}
+	if !slices.Equal(errutil.DevMessages(decoded), errutil.DevMessages(err)) {
+		t.Errorf("DevMessages() = %q, want %q", errutil.DevMessages(decoded), errutil.DevMessages(err))
+	}
+	if !slices.Equal(errutil.StackTrace(decoded), errutil.StackTrace(err)) {
+		t.Errorf("StackTrace() = %v, want %v", errutil.StackTrace(decoded), errutil.StackTrace(err))
+	}
+	if errutil.Cause(decoded).Error() != errutil.Cause(err).Error() {
+		t.Errorf("Cause() = %v, want %v", errutil.Cause(decoded), errutil.Cause(err))
+	}
+}
+
+func TestJSONError(t *testing.T) {
+	type response struct {
+		Err errutil.JSONError `json:"error"`
+	}
+
+	data, err := json.Marshal(response{Err: errutil.JSONError{Err: errutil.WithCode(io.EOF, "IO")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp response
+	if err = json.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if errutil.Code(resp.Err.Err) != "IO" || resp.Err.Err.Error() != "[IO] EOF" {
+		t.Errorf("decoded error = %v", resp.Err.Err)
+	}
+
+	nilErr := errors.New("not decoded")
+	if err := errutil.Decode([]byte("null"), &nilErr); err != nil || nilErr != nil {
+		t.Errorf("Decode(null) = %v, %v", nilErr, err)
+	}
+
+	var invalid error
+	if err := errutil.Decode([]byte("{"), &invalid); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Decode(invalid) error = %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Мультиошибка, объединяющая несколько ошибок, и потокобезопасный
+// накопитель ошибок для горутин
+
+package errutil
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// multiError - ошибка, объединяющая несколько ошибок
+type multiError struct {
+	errs []error
+}
+
+// Error - получение текстового представления ошибки
+func (e *multiError) Error() string {
+	if containsCauser(e) {
+		return errorString(e)
+	}
+
+	texts := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		texts = append(texts, err.Error())
+	}
+
+	return strings.Join(texts, "; ")
+}
+
+// Format - форматирование ошибки для пакета fmt, %+v выводит каждую ошибку со стеком
+func (e *multiError) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
+}
+
+// MarshalJSON - сериализация цепочки ошибок в JSON, восстанавливается через Decode
+func (e *multiError) MarshalJSON() ([]byte, error) {
+	return marshalError(e)
+}
+
+// LogValue - представление ошибки в виде группы атрибутов slog
+func (e *multiError) LogValue() slog.Value {
+	return SlogValue(e, false)
+}
+
+// Unwrap - получение списка объединённых ошибок для errors.Is и errors.As
+func (e *multiError) Unwrap() []error {
+	return e.errs
+}
+
+// Code - получение кода ошибки с наивысшим приоритетом согласно Config.CodePrecedence
+// среди кодов объединённых ошибок
+func (e *multiError) Code() string {
+	code, _ := findCode(e)
+
+	return code
+}
+
+// Join - объединение ошибок в одну мультиошибку.
+// Пустые ошибки отбрасываются, вложенные мультиошибки разворачиваются.
+// Если все ошибки пустые, возвращается nil.
+func Join(errs ...error) error {
+	result := make([]error, 0, len(errs))
+	for _, err := range errs {
+		switch e := err.(type) {
+		case nil:
+			continue
+		case *multiError:
+			result = append(result, e.errs...)
+		default:
+			result = append(result, err)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return &multiError{
+		errs: result,
+	}
+}
+
+/*
+----------
+*/
+
+// Collector - потокобезопасный накопитель ошибок.
+// Нулевое значение готово к использованию.
+type Collector struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// Add - добавление ошибки, пустые ошибки игнорируются
+func (c *Collector) Add(err error) {
+	if err == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.errs = append(c.errs, err)
+	c.mu.Unlock()
+}
+
+// Len - количество накопленных ошибок
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.errs)
+}
+
+// Errors - получение копии списка накопленных ошибок
+func (c *Collector) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.errs)
+}
+
+// Err - объединение накопленных ошибок в одну ошибку через Join.
+// Если ошибок не было, возвращается nil.
+func (c *Collector) Err() error {
+	return Join(c.Errors()...)
+}
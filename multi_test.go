@@ -0,0 +1,78 @@
+package errutil_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestJoin(t *testing.T) {
+	if errutil.Join(nil, nil) != nil {
+		t.Error("Join(nil, nil) != nil")
+	}
+
+	err := errutil.Join(
+		errutil.NewWithCode(errutil.CodeUser, "validation"),
+		nil,
+		errutil.WithCode(io.EOF, errutil.CodePanic),
+		errutil.New("critical"),
+	)
+
+	if got := errutil.Code(err); got != errutil.CodePanic {
+		t.Errorf("Code() = %q, want %q", got, errutil.CodePanic)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Error("errors.Is: io.EOF not found")
+	}
+
+	var multi interface{ Unwrap() []error }
+	if !errors.As(err, &multi) || len(multi.Unwrap()) != 3 {
+		t.Error("Unwrap() []error: expected 3 errors")
+	}
+
+	text := err.Error()
+	for _, part := range []string{"validation", "EOF", "critical"} {
+		if !strings.Contains(text, part) {
+			t.Errorf("Error() = %q does not contain %q", text, part)
+		}
+	}
+
+	verbose := fmt.Sprintf("%+v", err)
+	if stackEnabled && strings.Count(verbose, "stack:") != 2 {
+		t.Errorf("%%+v should contain 2 stacks:\n%s", verbose)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var c errutil.Collector
+
+	if c.Err() != nil {
+		t.Error("empty Collector.Err() != nil")
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				c.Add(errutil.Newf("err%v", i))
+			} else {
+				c.Add(nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.Len() != 50 {
+		t.Errorf("Len() = %d, want 50", c.Len())
+	}
+	if got := len(errutil.StackTrace(c.Err())); stackEnabled && got == 0 {
+		t.Error("StackTrace() of collected error is empty")
+	}
+}
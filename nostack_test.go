@@ -0,0 +1,40 @@
+//go:build errutil_nostack
+
+package errutil_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+// stackEnabled - признак захвата стека в текущей сборке
+const stackEnabled = false
+
+// Запуск: go test -tags errutil_nostack ./...
+func TestNoStack(t *testing.T) {
+	err := errutil.WithMessage(errutil.WithStack(errutil.NewWithCode(errutil.CodeUser, "dev")), "msg")
+
+	if errutil.Stack(err) != "" || len(errutil.StackTrace(err)) != 0 || errutil.StackTruncated(err) {
+		t.Error("stack is captured with errutil_nostack")
+	}
+	if len(errutil.NewStackTrace(0)) != 0 {
+		t.Error("NewStackTrace() is not empty")
+	}
+	if err.Error() != "[USER] dev (msg)" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if !errors.Is(err, errutil.NewWithCode(errutil.CodeUser)) {
+		t.Error("errors.Is() = false")
+	}
+	if s := fmt.Sprintf("%+v", err); s == "" {
+		t.Error("verbose format is empty")
+	}
+
+	perr := errutil.Safe(func() error { panic("boom") })
+	if errutil.Code(perr) != errutil.CodePanic || errutil.Stack(perr) != "" {
+		t.Errorf("Safe() = %q", perr)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Восстановление после паники с преобразованием в ошибку с кодом CodePanic
+// и стеком вызовов места возникновения паники
+
+package errutil
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// PanicError - значение, переданное в panic.
+// Доступно в цепочке ошибки, полученной при восстановлении, через errors.As.
+type PanicError struct {
+	Value any
+}
+
+// Error - получение текстового представления ошибки
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap - получение значения паники, если оно является ошибкой (например, runtime.Error)
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+
+	return err
+}
+
+// Recover - восстановление после паники для использования в defer:
+//
+//	defer errutil.Recover(&err)
+//
+// Паника преобразуется в ошибку с кодом CodePanic и записывается в err.
+// Recover должен вызываться непосредственно в defer.
+func Recover(err *error) {
+	if r := recover(); r != nil {
+		*err = fromPanic(r)
+	}
+}
+
+// FromPanic - преобразование значения, полученного из recover(), в ошибку с кодом CodePanic.
+// При вызове внутри отложенной функции стек вызовов берётся из места возникновения паники.
+func FromPanic(v any) error {
+	if v == nil {
+		return nil
+	}
+
+	return fromPanic(v)
+}
+
+// Safe - выполнение функции с преобразованием паники в ошибку с кодом CodePanic
+func Safe(fn func() error) (err error) {
+	defer Recover(&err)
+
+	return fn()
+}
+
+// Go - выполнение функции в отдельной горутине с преобразованием паники в ошибку.
+// Результат передаётся в канал, который закрывается после завершения функции.
+func Go(fn func() error) <-chan error {
+	result := make(chan error, 1)
+
+	go func() {
+		defer close(result)
+		result <- Safe(fn)
+	}()
+
+	return result
+}
+
+// fromPanic - создание ошибки с кодом CodePanic из значения паники
+func fromPanic(v any) error {
+	f := Default()
+
+	return newNode(&PanicError{Value: v}, segment{kind: segmentStack, code: CodePanic, factory: f, stack: f.panicStack()})
+}
+
+// panicStack - получение стека вызовов места возникновения паники согласно политике StackPolicy.
+// Фреймы обработчика паники отбрасываются до вызова runtime.gopanic включительно.
+func (f *Factory) panicStack() *stack {
+	if !stackCaptureEnabled {
+		return nil
+	}
+	if policy := f.cfg.StackPolicy; policy != nil && !policy(CodePanic) {
+		return nil
+	}
+
+	pcs, truncated := callers(3, f.maxStackTraceDepth())
+
+	for i, pc := range pcs {
+		fn := runtime.FuncForPC(pc - 1)
+		if fn != nil && fn.Name() == "runtime.gopanic" {
+			pcs = pcs[i+1:]
+			break
+		}
+	}
+
+	return f.newStack(pcs, truncated)
+}
@@ -0,0 +1,102 @@
+package errutil_test
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+var panicLine int
+
+func panicWithValue() (err error) {
+	defer errutil.Recover(&err)
+
+	_, _, panicLine, _ = runtime.Caller(0)
+	panic("boom")
+}
+
+func panicWithRuntimeError() error {
+	var m map[string]int
+	m["key"]++
+
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	err := panicWithValue()
+
+	if got := errutil.Code(err); got != errutil.CodePanic {
+		t.Errorf("Code() = %q, want %q", got, errutil.CodePanic)
+	}
+	if got := err.Error(); got != "[PANIC] panic: boom" {
+		t.Errorf("Error() = %q", got)
+	}
+
+	var pe *errutil.PanicError
+	if !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Errorf("errors.As(*PanicError) = %v", pe)
+	}
+
+	if !stackEnabled {
+		return
+	}
+	frames := errutil.StackTrace(err)
+	if len(frames) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+	last := frames[len(frames)-1]
+	if last.Function != "panicWithValue" || last.LineNumber != panicLine+1 {
+		t.Errorf("panic site = %s:%d %s, want line %d", last.File, last.LineNumber, last.Function, panicLine+1)
+	}
+}
+
+func TestSafe(t *testing.T) {
+	err := errutil.Safe(panicWithRuntimeError)
+
+	var re runtime.Error
+	if !errors.As(err, &re) {
+		t.Fatalf("errors.As(runtime.Error) failed for %v", err)
+	}
+
+	frames := errutil.StackTrace(err)
+	if stackEnabled && (len(frames) == 0 || frames[len(frames)-1].Function != "panicWithRuntimeError") {
+		t.Errorf("StackTrace() = %v", frames)
+	}
+
+	if err = errutil.Safe(func() error { return nil }); err != nil {
+		t.Errorf("Safe() = %v", err)
+	}
+}
+
+func TestRecoverStackPolicy(t *testing.T) {
+	defer errutil.SetDefault(nil)
+
+	errutil.SetDefault(errutil.NewFactory(errutil.Config{StackPolicy: errutil.StackNever()}))
+	err := errutil.Safe(panicWithRuntimeError)
+	if errutil.Code(err) != errutil.CodePanic || errutil.Stack(err) != "" {
+		t.Errorf("StackNever: Safe() = %q with stack %q", err, errutil.Stack(err))
+	}
+
+	errutil.SetDefault(errutil.NewFactory(errutil.Config{StackPolicy: errutil.StackForCodes(errutil.CodePanic)}))
+	if err = errutil.Safe(panicWithRuntimeError); stackEnabled && errutil.Stack(err) == "" {
+		t.Error("StackForCodes(PANIC): stack is not captured")
+	}
+}
+
+func TestGo(t *testing.T) {
+	err := <-errutil.Go(func() error {
+		panic(errutil.NewWithCode(errutil.CodeUser, "inner"))
+	})
+
+	if got := errutil.Code(err); got != errutil.CodePanic {
+		t.Errorf("Code() = %q, want %q", got, errutil.CodePanic)
+	}
+	if !errors.Is(err, errutil.NewWithCode(errutil.CodeUser)) {
+		t.Error("errors.Is: inner error not found")
+	}
+	if errutil.FromPanic(nil) != nil {
+		t.Error("FromPanic(nil) != nil")
+	}
+}
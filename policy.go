@@ -0,0 +1,69 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Политики захвата стека вызовов при создании ошибок
+
+package errutil
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+)
+
+// StackPolicy - политика захвата стека вызовов.
+// Возвращает признак необходимости захвата стека для ошибки с кодом code.
+// Для стеков, захватываемых до появления кода (например, в Group.Go), code пуст.
+// При сборке с тегом errutil_nostack стек не захватывается независимо от политики.
+type StackPolicy func(code string) bool
+
+// StackAlways - политика, захватывающая стек для всех ошибок
+func StackAlways() StackPolicy {
+	return func(string) bool { return true }
+}
+
+// StackNever - политика, отключающая захват стека
+func StackNever() StackPolicy {
+	return func(string) bool { return false }
+}
+
+// StackSampled - политика, захватывающая стек для доли rate ошибок (от 0 до 1)
+func StackSampled(rate float64) StackPolicy {
+	switch {
+	case rate <= 0:
+		return StackNever()
+	case rate >= 1:
+		return StackAlways()
+	}
+
+	return func(string) bool {
+		return rand.Float64() < rate
+	}
+}
+
+// StackForCodes - политика, захватывающая стек только для ошибок с перечисленными кодами,
+// например, StackForCodes(CodePanic, CodeCritical)
+func StackForCodes(codes ...string) StackPolicy {
+	codes = slices.Clone(codes)
+
+	return func(code string) bool {
+		return slices.Contains(codes, code)
+	}
+}
+
+// StackByCode - политика, выбирающая политику по коду ошибки.
+// Для кодов, отсутствующих в policies, используется fallback (при nil - StackAlways).
+func StackByCode(policies map[string]StackPolicy, fallback StackPolicy) StackPolicy {
+	policies = maps.Clone(policies)
+	if fallback == nil {
+		fallback = StackAlways()
+	}
+
+	return func(code string) bool {
+		if policy, ok := policies[code]; ok && policy != nil {
+			return policy(code)
+		}
+
+		return fallback(code)
+	}
+}
@@ -0,0 +1,71 @@
+package errutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestStackPolicies(t *testing.T) {
+	requireStack(t)
+
+	tests := []struct {
+		name   string
+		policy errutil.StackPolicy
+		code   string
+		want   bool
+	}{
+		{"always", errutil.StackAlways(), errutil.CodeUser, true},
+		{"never", errutil.StackNever(), errutil.CodePanic, false},
+		{"sampled 0", errutil.StackSampled(0), errutil.CodeCritical, false},
+		{"sampled 1", errutil.StackSampled(1), errutil.CodeCritical, true},
+		{"for codes: critical", errutil.StackForCodes(errutil.CodeCritical, errutil.CodePanic), errutil.CodeCritical, true},
+		{"for codes: user", errutil.StackForCodes(errutil.CodeCritical, errutil.CodePanic), errutil.CodeUser, false},
+		{"by code: user", errutil.StackByCode(map[string]errutil.StackPolicy{errutil.CodeUser: errutil.StackNever()}, nil), errutil.CodeUser, false},
+		{"by code: fallback", errutil.StackByCode(map[string]errutil.StackPolicy{errutil.CodeUser: errutil.StackNever()}, nil), errutil.CodeCritical, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := errutil.NewFactory(errutil.Config{StackPolicy: tt.policy})
+			err := f.NewWithCode(tt.code, "test")
+
+			if got := errutil.Stack(err) != ""; got != tt.want {
+				t.Errorf("stack captured = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStackSampled(t *testing.T) {
+	policy := errutil.StackSampled(0.5)
+
+	n := 0
+	for range 1000 {
+		if policy(errutil.CodeUser) {
+			n++
+		}
+	}
+
+	if n < 350 || n > 650 {
+		t.Errorf("StackSampled(0.5) captured %d of 1000", n)
+	}
+}
+
+func TestStackPolicyWithStack(t *testing.T) {
+	requireStack(t)
+
+	f := errutil.NewFactory(errutil.Config{StackPolicy: errutil.StackForCodes(errutil.CodeCritical)})
+
+	// Код для политики WithStack берётся из оборачиваемой ошибки
+	user := f.WithStack(errutil.WithCode(errors.New("invalid"), errutil.CodeUser))
+	if errutil.Stack(user) != "" {
+		t.Error("WithStack(USER): stack is captured")
+	}
+
+	critical := f.WithStack(errutil.WithCode(errors.New("failed"), errutil.CodeCritical))
+	if errutil.Stack(critical) == "" {
+		t.Error("WithStack(CRITICAL): stack is not captured")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Формирование HTTP-ответов в формате RFC 9457 (Problem Details)
+// из ошибок errutil и обратное преобразование на стороне клиента
+
+package errutil
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// ProblemContentType - тип содержимого ответа с описанием ошибки
+const ProblemContentType = "application/problem+json"
+
+// Problem - описание ошибки в формате RFC 9457
+type Problem struct {
+	// URI типа проблемы
+	Type string `json:"type,omitempty"`
+
+	// Краткое описание типа проблемы
+	Title string `json:"title,omitempty"`
+
+	// HTTP-статус ответа
+	Status int `json:"status,omitempty"`
+
+	// Сообщение для пользователя
+	Detail string `json:"detail,omitempty"`
+
+	// URI конкретного случая возникновения проблемы
+	Instance string `json:"instance,omitempty"`
+
+	// Код ошибки errutil
+	Code string `json:"code,omitempty"`
+
+	// Dev-сообщения, только в отладочном режиме
+	DevMessages []string `json:"dev_messages,omitempty"`
+
+	// Стек вызовов, только в отладочном режиме
+	Stack []StackFrame `json:"stack,omitempty"`
+}
+
+// NewProblem - построение описания ошибки в формате RFC 9457.
+// HTTP-статус и тип проблемы определяются по реестру кодов ошибок.
+func NewProblem(err error) *Problem {
+	status := HTTPStatus(err)
+
+	p := &Problem{
+		Type:   DocURL(err),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: UserMessage(err),
+		Code:   Code(err),
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+
+	if factoryOf(err).cfg.ProblemDebug {
+		p.DevMessages = DevMessages(err)
+		p.Stack = StackTrace(err)
+	}
+
+	return p
+}
+
+// Err - преобразование описания проблемы в ошибку errutil с кодом,
+// сообщением для пользователя, dev-сообщениями и стеком вызовов из ответа
+func (p *Problem) Err() error {
+	code := p.Code
+	if code == "" {
+		code = statusCode(p.Status)
+	}
+
+	err := newNode(nil, segment{kind: segmentStack, code: code, stack: stackFromFrames(p.Stack, false)})
+
+	if len(p.DevMessages) > 0 {
+		err = newNode(err, segment{kind: segmentDev, dev: p.DevMessages})
+	}
+
+	if p.Detail != "" {
+		err = newNode(err, segment{kind: segmentMessage, msg: p.Detail})
+	}
+
+	return err
+}
+
+// WriteProblem - запись ошибки в HTTP-ответ в формате application/problem+json
+func WriteProblem(w http.ResponseWriter, err error) {
+	p := NewProblem(err)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// ProblemHandler - HTTP-обработчик, возвращающий ошибку.
+// Возвращённая ошибка записывается в ответ через WriteProblem.
+type ProblemHandler func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP - реализация интерфейса http.Handler
+func (h ProblemHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		WriteProblem(w, err)
+	}
+}
+
+// ParseProblem - преобразование HTTP-ответа с ошибкой в ошибку errutil.
+// Для успешных ответов возвращается nil. Тело ответа считывается, но не закрывается.
+func ParseProblem(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, Default().maxProblemSize()))
+	if err != nil {
+		return WithDevMessagef(err, "read problem response: %s", resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == ProblemContentType {
+		p := &Problem{}
+		if err = json.Unmarshal(body, p); err == nil {
+			if p.Status == 0 {
+				p.Status = resp.StatusCode
+			}
+			return p.Err()
+		}
+	}
+
+	return NewWithCodef(statusCode(resp.StatusCode), "http response: %s", resp.Status)
+}
+
+// statusCode - получение кода ошибки по HTTP-статусу
+func statusCode(status int) string {
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		return CodeUser
+	}
+
+	return Default().defaultCode()
+}
@@ -0,0 +1,73 @@
+package errutil_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestProblem(t *testing.T) {
+	handler := errutil.ProblemHandler(func(w http.ResponseWriter, r *http.Request) error {
+		err := errutil.NewWithCode(errutil.CodeUser, "invalid id")
+		return errutil.WithMessage(err, "Неверный идентификатор")
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/x", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != errutil.ProblemContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	var p errutil.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != "about:blank" || p.Title != "Bad Request" || p.Status != http.StatusBadRequest ||
+		p.Detail != "Неверный идентификатор" || p.Code != errutil.CodeUser {
+		t.Errorf("problem = %+v", p)
+	}
+	if p.DevMessages != nil || p.Stack != nil {
+		t.Error("dev messages and stack must be hidden without ProblemDebug")
+	}
+
+	err := errutil.ParseProblem(rec.Result())
+	if errutil.Code(err) != errutil.CodeUser || errutil.Message(err) != "Неверный идентификатор" {
+		t.Errorf("ParseProblem() = %v", err)
+	}
+}
+
+func TestProblemDebug(t *testing.T) {
+	defer errutil.SetDefault(nil)
+	errutil.SetDefault(errutil.NewFactory(errutil.Config{ProblemDebug: true}))
+
+	rec := httptest.NewRecorder()
+	errutil.WriteProblem(rec, errutil.New("db timeout"))
+
+	err := errutil.ParseProblem(rec.Result())
+	if errutil.Code(err) != errutil.CodeCritical {
+		t.Errorf("Code() = %q", errutil.Code(err))
+	}
+	if errutil.Message(err) != errutil.DefaultUserMessage {
+		t.Errorf("Message() = %q", errutil.Message(err))
+	}
+	if !slices.Equal(errutil.DevMessages(err), []string{"db timeout"}) {
+		t.Errorf("DevMessages() = %q", errutil.DevMessages(err))
+	}
+	if stackEnabled && len(errutil.StackTrace(err)) == 0 {
+		t.Error("StackTrace() is empty")
+	}
+
+	rec = httptest.NewRecorder()
+	http.NotFound(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err = errutil.ParseProblem(rec.Result()); errutil.Code(err) != errutil.CodeUser {
+		t.Errorf("ParseProblem(404) = %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Реестр кодов ошибок с метаданными: HTTP-статус, код gRPC,
+// уровень серьёзности, признак повторяемости и сообщение для пользователя
+
+package errutil
+
+import (
+	"iter"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SeverityLevel - уровень серьёзности ошибки
+type SeverityLevel int
+
+const (
+	SeverityUnknown SeverityLevel = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+// String - получение текстового представления уровня серьёзности
+func (s SeverityLevel) String() string {
+	switch s {
+	case SeverityDebug:
+		return "DEBUG"
+	case SeverityInfo:
+		return "INFO"
+	case SeverityWarning:
+		return "WARNING"
+	case SeverityError:
+		return "ERROR"
+	case SeverityCritical:
+		return "CRITICAL"
+	}
+
+	return "UNKNOWN"
+}
+
+// Retry - признак того, что операцию, завершившуюся ошибкой, можно повторить
+type Retry int
+
+const (
+	// Признак не задан и наследуется от предка кода
+	RetryUnknown Retry = iota
+
+	// Операцию можно повторить
+	RetryAllowed
+
+	// Операцию повторять нельзя, в том числе если повтор разрешён предку кода
+	RetryForbidden
+)
+
+// GRPCCode - код статуса gRPC, значения совпадают с google.golang.org/grpc/codes
+type GRPCCode uint32
+
+const (
+	GRPCOK                 GRPCCode = 0
+	GRPCCanceled           GRPCCode = 1
+	GRPCUnknown            GRPCCode = 2
+	GRPCInvalidArgument    GRPCCode = 3
+	GRPCDeadlineExceeded   GRPCCode = 4
+	GRPCNotFound           GRPCCode = 5
+	GRPCAlreadyExists      GRPCCode = 6
+	GRPCPermissionDenied   GRPCCode = 7
+	GRPCResourceExhausted  GRPCCode = 8
+	GRPCFailedPrecondition GRPCCode = 9
+	GRPCAborted            GRPCCode = 10
+	GRPCOutOfRange         GRPCCode = 11
+	GRPCUnimplemented      GRPCCode = 12
+	GRPCInternal           GRPCCode = 13
+	GRPCUnavailable        GRPCCode = 14
+	GRPCDataLoss           GRPCCode = 15
+	GRPCUnauthenticated    GRPCCode = 16
+)
+
+// CodeInfo - метаданные кода ошибки
+type CodeInfo struct {
+	// Код ошибки
+	Code string
+
+	// HTTP-статус ответа
+	HTTPStatus int
+
+	// Код статуса gRPC
+	GRPCCode GRPCCode
+
+	// Уровень серьёзности
+	Severity SeverityLevel
+
+	// Признак того, что операцию можно повторить
+	Retryable Retry
+
+	// Сообщение для пользователя по умолчанию
+	UserMessage string
+
+	// Ссылка на документацию по ошибке
+	DocURL string
+
+	// Родительский код. Если не задан, родителем кода "DB.TIMEOUT" считается "DB".
+	// Незаполненные поля наследуются от ближайшего зарегистрированного предка.
+	Parent string
+}
+
+// codeRegistry - реестр метаданных кодов ошибок
+var codeRegistry = struct {
+	sync.RWMutex
+	codes map[string]CodeInfo
+}{
+	codes: map[string]CodeInfo{
+		CodePanic: {
+			Code:       CodePanic,
+			HTTPStatus: http.StatusInternalServerError,
+			GRPCCode:   GRPCInternal,
+			Severity:   SeverityCritical,
+		},
+		CodeCritical: {
+			Code:       CodeCritical,
+			HTTPStatus: http.StatusInternalServerError,
+			GRPCCode:   GRPCInternal,
+			Severity:   SeverityError,
+		},
+		CodeUser: {
+			Code:       CodeUser,
+			HTTPStatus: http.StatusBadRequest,
+			GRPCCode:   GRPCInvalidArgument,
+			Severity:   SeverityWarning,
+		},
+	},
+}
+
+// RegisterCode - регистрация метаданных кода ошибки, повторная регистрация заменяет предыдущую
+func RegisterCode(info CodeInfo) {
+	codeRegistry.Lock()
+	codeRegistry.codes[info.Code] = info
+	codeRegistry.Unlock()
+}
+
+// LookupCode - получение метаданных кода ошибки из реестра.
+// Незаполненные поля наследуются от ближайшего предка, в котором они заполнены,
+// ok - признак регистрации кода или одного из его предков.
+func LookupCode(code string) (CodeInfo, bool) {
+	codeRegistry.RLock()
+	defer codeRegistry.RUnlock()
+
+	info := CodeInfo{Code: code, Parent: codeParent(code)}
+	found := false
+
+	for ancestor := range codeAncestors(code) {
+		parent, ok := codeRegistry.codes[ancestor]
+		if !ok {
+			continue
+		}
+
+		found = true
+		info = inheritCodeInfo(info, parent)
+	}
+	if !found {
+		return CodeInfo{}, false
+	}
+
+	return info, true
+}
+
+// CodeParent - получение родительского кода: зарегистрированного Parent
+// или части кода до последней точки, для кода верхнего уровня - пустая строка
+func CodeParent(code string) string {
+	codeRegistry.RLock()
+	defer codeRegistry.RUnlock()
+
+	return codeParent(code)
+}
+
+// IsCode - проверка наличия в цепочке ошибок кода code или любого из его потомков:
+// IsCode(err, "DB") выполняется для кодов "DB", "DB.TIMEOUT" и "DB.CONSTRAINT.UNIQUE".
+// Как и в Codes, учитываются только явно заданные коды
+func IsCode(err error, code string) bool {
+	codes := Codes(err)
+
+	codeRegistry.RLock()
+	defer codeRegistry.RUnlock()
+
+	for _, c := range codes {
+		for ancestor := range codeAncestors(c) {
+			if ancestor == code {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// codeParent - получение родительского кода, вызывается под блокировкой реестра
+func codeParent(code string) string {
+	if info, ok := codeRegistry.codes[code]; ok && info.Parent != "" {
+		return info.Parent
+	}
+
+	if i := strings.LastIndexByte(code, '.'); i > 0 {
+		return code[:i]
+	}
+
+	return ""
+}
+
+// codeAncestors - перебор кода и его предков от ближайшего к корневому,
+// вызывается под блокировкой реестра. Цикл в зарегистрированных Parent обрывается.
+func codeAncestors(code string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		var seen []string
+		for code != "" && !slices.Contains(seen, code) {
+			if !yield(code) {
+				return
+			}
+
+			seen = append(seen, code)
+			code = codeParent(code)
+		}
+	}
+}
+
+// inheritCodeInfo - заполнение незаполненных полей info значениями предка parent
+func inheritCodeInfo(info, parent CodeInfo) CodeInfo {
+	if info.HTTPStatus == 0 {
+		info.HTTPStatus = parent.HTTPStatus
+	}
+	if info.GRPCCode == GRPCOK {
+		info.GRPCCode = parent.GRPCCode
+	}
+	if info.Severity == SeverityUnknown {
+		info.Severity = parent.Severity
+	}
+	if info.Retryable == RetryUnknown {
+		info.Retryable = parent.Retryable
+	}
+	if info.UserMessage == "" {
+		info.UserMessage = parent.UserMessage
+	}
+	if info.DocURL == "" {
+		info.DocURL = parent.DocURL
+	}
+
+	return info
+}
+
+// codeInfo - получение метаданных кода ошибки из цепочки
+func codeInfo(err error) CodeInfo {
+	info, _ := LookupCode(Code(err))
+
+	return info
+}
+
+// HTTPStatus - получение HTTP-статуса ошибки по её коду, по умолчанию 500
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	if status := codeInfo(err).HTTPStatus; status != 0 {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// GRPCStatus - получение кода статуса gRPC ошибки по её коду.
+// Если код gRPC не зарегистрирован, он определяется по HTTP-статусу, по умолчанию Unknown.
+func GRPCStatus(err error) GRPCCode {
+	if err == nil {
+		return GRPCOK
+	}
+
+	info := codeInfo(err)
+	if info.GRPCCode != GRPCOK {
+		return info.GRPCCode
+	}
+
+	return grpcFromHTTP(info.HTTPStatus)
+}
+
+// grpcFromHTTP - код статуса gRPC, соответствующий HTTP-статусу
+func grpcFromHTTP(status int) GRPCCode {
+	switch status {
+	case http.StatusBadRequest:
+		return GRPCInvalidArgument
+	case http.StatusUnauthorized:
+		return GRPCUnauthenticated
+	case http.StatusForbidden:
+		return GRPCPermissionDenied
+	case http.StatusNotFound:
+		return GRPCNotFound
+	case http.StatusConflict:
+		return GRPCAborted
+	case http.StatusTooManyRequests:
+		return GRPCResourceExhausted
+	case http.StatusNotImplemented:
+		return GRPCUnimplemented
+	case http.StatusServiceUnavailable:
+		return GRPCUnavailable
+	case http.StatusGatewayTimeout:
+		return GRPCDeadlineExceeded
+	case http.StatusInternalServerError:
+		return GRPCInternal
+	}
+
+	return GRPCUnknown
+}
+
+// Severity - получение уровня серьёзности ошибки по её коду, по умолчанию SeverityError
+func Severity(err error) SeverityLevel {
+	if severity := codeInfo(err).Severity; severity != SeverityUnknown {
+		return severity
+	}
+
+	return SeverityError
+}
+
+// IsRetryable - признак того, что операцию, завершившуюся ошибкой, можно повторить
+func IsRetryable(err error) bool {
+	return codeInfo(err).Retryable == RetryAllowed
+}
+
+// DocURL - получение ссылки на документацию по коду ошибки
+func DocURL(err error) string {
+	return codeInfo(err).DocURL
+}
+
+// UserMessage - получение сообщения для пользователя с учётом сообщения
+// по умолчанию, зарегистрированного для кода ошибки, и сообщения по умолчанию (Config.DefaultUserMessage)
+func UserMessage(err error) string {
+	if msg := codeInfo(err).UserMessage; msg != "" {
+		return Message(err, msg)
+	}
+
+	return Message(err, factoryOf(err).defaultUserMessage())
+}
@@ -0,0 +1,199 @@
+package errutil_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestRegistry(t *testing.T) {
+	errutil.RegisterCode(errutil.CodeInfo{
+		Code:        "ORDER_LOCKED",
+		HTTPStatus:  http.StatusConflict,
+		GRPCCode:    errutil.GRPCAborted,
+		Severity:    errutil.SeverityWarning,
+		Retryable:   errutil.RetryAllowed,
+		UserMessage: "Заказ обрабатывается, повторите позже",
+		DocURL:      "https://example.com/errors/order-locked",
+	})
+
+	err := errutil.WithDevMessage(errutil.NewWithCode("ORDER_LOCKED", "lock order"), "update order")
+
+	if got := errutil.HTTPStatus(err); got != http.StatusConflict {
+		t.Errorf("HTTPStatus() = %d", got)
+	}
+	if got := errutil.GRPCStatus(err); got != errutil.GRPCAborted {
+		t.Errorf("GRPCStatus() = %d", got)
+	}
+	if got := errutil.Severity(err); got != errutil.SeverityWarning {
+		t.Errorf("Severity() = %v", got)
+	}
+	if !errutil.IsRetryable(err) {
+		t.Error("IsRetryable() = false")
+	}
+	if got := errutil.UserMessage(err); got != "Заказ обрабатывается, повторите позже" {
+		t.Errorf("UserMessage() = %q", got)
+	}
+
+	rec := httptest.NewRecorder()
+	errutil.WriteProblem(rec, err)
+
+	var p errutil.Problem
+	if err = json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != http.StatusConflict || p.Type != "https://example.com/errors/order-locked" {
+		t.Errorf("problem = %+v", p)
+	}
+}
+
+func TestRegistryDefaults(t *testing.T) {
+	if got := errutil.HTTPStatus(errutil.New("test")); got != http.StatusInternalServerError {
+		t.Errorf("HTTPStatus(CRITICAL) = %d", got)
+	}
+	if got := errutil.HTTPStatus(errutil.NewWithCode(errutil.CodeUser)); got != http.StatusBadRequest {
+		t.Errorf("HTTPStatus(USER) = %d", got)
+	}
+	if got := errutil.GRPCStatus(errutil.NewWithCode("UNREGISTERED")); got != errutil.GRPCUnknown {
+		t.Errorf("GRPCStatus(UNREGISTERED) = %d", got)
+	}
+	if got := errutil.Severity(errutil.NewWithCode(errutil.CodePanic)); got != errutil.SeverityCritical {
+		t.Errorf("Severity(PANIC) = %v", got)
+	}
+	if errutil.IsRetryable(errutil.New("test")) {
+		t.Error("IsRetryable(CRITICAL) = true")
+	}
+	if got := errutil.HTTPStatus(nil); got != http.StatusOK {
+		t.Errorf("HTTPStatus(nil) = %d", got)
+	}
+}
+
+func TestRegistryGRPCFallback(t *testing.T) {
+	errutil.RegisterCode(errutil.CodeInfo{Code: "GRPC_FROM_HTTP", HTTPStatus: http.StatusNotFound})
+	errutil.RegisterCode(errutil.CodeInfo{Code: "GRPC_NO_STATUS", Severity: errutil.SeverityInfo})
+
+	if got := errutil.GRPCStatus(errutil.NewWithCode("GRPC_FROM_HTTP")); got != errutil.GRPCNotFound {
+		t.Errorf("GRPCStatus(404) = %d, want %d", got, errutil.GRPCNotFound)
+	}
+	if got := errutil.GRPCStatus(errutil.NewWithCode("GRPC_NO_STATUS")); got != errutil.GRPCUnknown {
+		t.Errorf("GRPCStatus(no status) = %d, want %d", got, errutil.GRPCUnknown)
+	}
+}
+
+func TestRegistryHierarchy(t *testing.T) {
+	errutil.RegisterCode(errutil.CodeInfo{
+		Code:       "DB",
+		HTTPStatus: http.StatusServiceUnavailable,
+		GRPCCode:   errutil.GRPCUnavailable,
+		Severity:   errutil.SeverityError,
+		Retryable:  errutil.RetryAllowed,
+	})
+	errutil.RegisterCode(errutil.CodeInfo{
+		Code:       "DB.CONSTRAINT",
+		HTTPStatus: http.StatusConflict,
+	})
+	errutil.RegisterCode(errutil.CodeInfo{
+		Code:   "UNIQUE_VIOLATION",
+		Parent: "DB.CONSTRAINT",
+	})
+
+	tests := []struct {
+		code   string
+		parent string
+		status int
+	}{
+		{"DB.TIMEOUT", "DB", http.StatusServiceUnavailable},
+		{"DB.CONSTRAINT.UNIQUE", "DB.CONSTRAINT", http.StatusConflict},
+		{"UNIQUE_VIOLATION", "DB.CONSTRAINT", http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := errutil.CodeParent(tt.code); got != tt.parent {
+				t.Errorf("CodeParent() = %q, want %q", got, tt.parent)
+			}
+
+			err := errutil.NewWithCode(tt.code, "query")
+			if got := errutil.HTTPStatus(err); got != tt.status {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.status)
+			}
+			if got := errutil.GRPCStatus(err); got != errutil.GRPCUnavailable {
+				t.Errorf("GRPCStatus() = %d", got)
+			}
+			if !errutil.IsRetryable(err) {
+				t.Error("IsRetryable() = false")
+			}
+			if !errutil.IsCode(err, "DB") {
+				t.Error(`IsCode("DB") = false`)
+			}
+		})
+	}
+
+	if _, ok := errutil.LookupCode("DBX.TIMEOUT"); ok {
+		t.Error(`LookupCode("DBX.TIMEOUT") found`)
+	}
+	if errutil.IsCode(errutil.NewWithCode("DBX"), "DB") {
+		t.Error(`IsCode("DBX", "DB") = true`)
+	}
+
+	// Цикл в зарегистрированных родителях
+	errutil.RegisterCode(errutil.CodeInfo{Code: "LOOP.A", Parent: "LOOP.B"})
+	errutil.RegisterCode(errutil.CodeInfo{Code: "LOOP.B", Parent: "LOOP.A"})
+	if errutil.IsCode(errutil.NewWithCode("LOOP.A"), "LOOP") {
+		t.Error(`IsCode("LOOP.A", "LOOP") = true`)
+	}
+}
+
+func TestRegistryRetryableOverride(t *testing.T) {
+	errutil.RegisterCode(errutil.CodeInfo{Code: "QUEUE", Retryable: errutil.RetryAllowed})
+	errutil.RegisterCode(errutil.CodeInfo{Code: "QUEUE.POISON", Retryable: errutil.RetryForbidden})
+
+	tests := []struct {
+		code      string
+		retryable bool
+	}{
+		{"QUEUE", true},
+		{"QUEUE.FULL", true},
+		{"QUEUE.POISON", false},
+		{"QUEUE.POISON.JSON", false},
+	}
+
+	for _, tt := range tests {
+		if got := errutil.IsRetryable(errutil.NewWithCode(tt.code)); got != tt.retryable {
+			t.Errorf("IsRetryable(%s) = %v, want %v", tt.code, got, tt.retryable)
+		}
+	}
+}
+
+func TestCodes(t *testing.T) {
+	err := errutil.WithCode(errutil.NewWithCode("DB.TIMEOUT", "query"), errutil.CodeUser)
+	err = errutil.Join(err, errutil.WithCode(errors.New("foreign"), "DB.CONSTRAINT.UNIQUE"), errutil.NewWithCode(errutil.CodeUser))
+
+	codes := errutil.Codes(err)
+	if !slices.Equal(codes, []string{errutil.CodeUser, "DB.TIMEOUT", "DB.CONSTRAINT.UNIQUE"}) {
+		t.Errorf("Codes() = %q", codes)
+	}
+	if !errutil.IsCode(err, "DB.CONSTRAINT") || errutil.IsCode(err, "DB.CONSTRAINT.FOREIGN") {
+		t.Error("IsCode() mismatch for multi error")
+	}
+	if errutil.Codes(errors.New("foreign")) != nil {
+		t.Error("Codes(foreign) != nil")
+	}
+
+	// Код по умолчанию, подставленный New, не учитывается
+	wrapped := errutil.WithCode(errutil.New("q"), "DB.TIMEOUT")
+	if codes := errutil.Codes(wrapped); !slices.Equal(codes, []string{"DB.TIMEOUT"}) {
+		t.Errorf("Codes(WithCode(New)) = %q", codes)
+	}
+	if errutil.IsCode(wrapped, errutil.DefaultCode) || errutil.IsCode(errutil.New("q"), errutil.DefaultCode) {
+		t.Error("IsCode(DefaultCode) = true for default code")
+	}
+	if !errutil.IsCode(errutil.NewWithCode(errutil.DefaultCode), errutil.DefaultCode) {
+		t.Error("IsCode(DefaultCode) = false for explicit code")
+	}
+}
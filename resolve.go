@@ -0,0 +1,165 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Стратегии определения кода ошибки, когда цепочка содержит несколько кодов
+
+package errutil
+
+import "slices"
+
+// CodeResolver - стратегия выбора кода ошибки из кодов цепочки,
+// перечисленных от внешнего уровня к внутреннему.
+// Пустая строка означает отсутствие кода, тогда используется код по умолчанию.
+type CodeResolver func(codes []string) string
+
+// CodeOutermost - код внешнего уровня цепочки (по умолчанию)
+func CodeOutermost() CodeResolver {
+	return func(codes []string) string {
+		if len(codes) == 0 {
+			return ""
+		}
+
+		return codes[0]
+	}
+}
+
+// CodeInnermost - код самого внутреннего уровня цепочки
+func CodeInnermost() CodeResolver {
+	return func(codes []string) string {
+		if len(codes) == 0 {
+			return ""
+		}
+
+		return codes[len(codes)-1]
+	}
+}
+
+// CodeHighestSeverity - код с наибольшим уровнем серьёзности по реестру кодов,
+// из кодов с одинаковым уровнем выбирается внешний
+func CodeHighestSeverity() CodeResolver {
+	return func(codes []string) string {
+		var code string
+		severity := SeverityUnknown
+
+		for _, c := range codes {
+			if s := codeSeverity(c); s > severity {
+				code, severity = c, s
+			}
+		}
+
+		return code
+	}
+}
+
+// CodeByPrecedence - код с наивысшим приоритетом: чем раньше код в списке precedence,
+// тем выше его приоритет. При пустом precedence используется Config.CodePrecedence экземпляра Default.
+// Из кодов вне списка выбирается внешний.
+func CodeByPrecedence(precedence ...string) CodeResolver {
+	return func(codes []string) string {
+		list := precedence
+		if len(list) == 0 {
+			list = Default().codePrecedence()
+		}
+
+		return mostPreceding(codes, list)
+	}
+}
+
+// CodeWith - получение кода ошибки по стратегии resolve вместо стратегии Config.CodeResolver.
+// При resolve == nil используется Config.CodeResolver.
+func CodeWith(err error, resolve CodeResolver) string {
+	if resolve == nil {
+		return Code(err)
+	}
+
+	f := factoryOf(err)
+	if code, ok := resolveCode(err, resolve, f.codePrecedence()); ok {
+		return code
+	}
+
+	return f.defaultCode()
+}
+
+// resolveCode - определение кода ошибки по стратегии resolve.
+// Стратегия применяется к явно заданным кодам каждой ветки, код по умолчанию,
+// подставленный New, используется, только если явных кодов в ветке нет.
+// Коды веток мультиошибки объединяются в один код её уровня по приоритету precedence.
+func resolveCode(err error, resolve CodeResolver, precedence []string) (string, bool) {
+	code := fold(err, func(l *layer) (chainCode, bool, walkAction) {
+		switch {
+		case l.truncated != truncationNone:
+		case l.seg != nil:
+			return chainCode{code: l.seg.code, implicit: l.seg.implicit}, l.seg.code != "", walkNext
+		case l.multi == nil:
+			if e, ok := l.err.(Coder); ok {
+				code := e.Code()
+				return chainCode{code: code}, code != "", walkNext
+			}
+		}
+
+		return chainCode{}, false, walkNext
+	}, func(codes []chainCode) (chainCode, bool) {
+		return joinCodes(codes, resolve)
+	}, func(codes []chainCode) (chainCode, bool) {
+		return joinCodes(codes, func(codes []string) string {
+			return mostPreceding(codes, precedence)
+		})
+	})
+
+	return code.code, code.code != ""
+}
+
+// chainCode - код уровня цепочки и признак кода по умолчанию
+type chainCode struct {
+	code     string
+	implicit bool
+}
+
+// joinCodes - выбор кода из явно заданных кодов стратегией resolve,
+// при их отсутствии - внешнего кода по умолчанию
+func joinCodes(codes []chainCode, resolve CodeResolver) (chainCode, bool) {
+	explicit := make([]string, 0, len(codes))
+	for _, c := range codes {
+		if !c.implicit {
+			explicit = append(explicit, c.code)
+		}
+	}
+
+	if len(explicit) > 0 {
+		code := resolve(explicit)
+		return chainCode{code: code}, code != ""
+	}
+	if len(codes) > 0 {
+		return codes[0], true
+	}
+
+	return chainCode{}, false
+}
+
+// mostPreceding - выбор кода с наивысшим приоритетом в списке precedence
+func mostPreceding(codes, precedence []string) string {
+	var code string
+	rank := -1
+
+	for _, c := range codes {
+		r := slices.Index(precedence, c)
+		if r < 0 {
+			r = len(precedence)
+		}
+
+		if rank < 0 || r < rank {
+			code, rank = c, r
+		}
+	}
+
+	return code
+}
+
+// codeSeverity - уровень серьёзности кода по реестру, по умолчанию SeverityError
+func codeSeverity(code string) SeverityLevel {
+	if info, ok := LookupCode(code); ok && info.Severity != SeverityUnknown {
+		return info.Severity
+	}
+
+	return SeverityError
+}
@@ -0,0 +1,106 @@
+package errutil_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestCodeResolver(t *testing.T) {
+	// Обработчик добавил код USER поверх паники
+	err := errutil.WithCode(errutil.NewWithCode(errutil.CodePanic, "nil map"), errutil.CodeUser)
+	err = errutil.WithCode(err, "HANDLER")
+
+	tests := []struct {
+		name    string
+		resolve errutil.CodeResolver
+		want    string
+	}{
+		{"Outermost", errutil.CodeOutermost(), "HANDLER"},
+		{"Innermost", errutil.CodeInnermost(), errutil.CodePanic},
+		{"HighestSeverity", errutil.CodeHighestSeverity(), errutil.CodePanic},
+		{"Precedence", errutil.CodeByPrecedence(), errutil.CodePanic},
+		{"CustomPrecedence", errutil.CodeByPrecedence(errutil.CodeUser), errutil.CodeUser},
+		{"Custom", func(codes []string) string { return codes[1] }, errutil.CodeUser},
+		{"Empty", func([]string) string { return "" }, errutil.DefaultCode},
+		{"Nil", nil, "HANDLER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errutil.CodeWith(err, tt.resolve); got != tt.want {
+				t.Errorf("CodeWith() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := errutil.CodeWith(errors.New("foreign"), errutil.CodeInnermost()); got != errutil.DefaultCode {
+		t.Errorf("CodeWith(foreign) = %q", got)
+	}
+}
+
+func TestCodeResolverDefaultCode(t *testing.T) {
+	// Код по умолчанию, подставленный New, не участвует в выборе стратегией
+	err := errutil.WithCode(errutil.New("bad input"), errutil.CodeUser)
+
+	for name, resolve := range map[string]errutil.CodeResolver{
+		"Outermost":       errutil.CodeOutermost(),
+		"Innermost":       errutil.CodeInnermost(),
+		"HighestSeverity": errutil.CodeHighestSeverity(),
+		"Precedence":      errutil.CodeByPrecedence(),
+	} {
+		if got := errutil.CodeWith(err, resolve); got != errutil.CodeUser {
+			t.Errorf("%s: CodeWith() = %q, want %q", name, got, errutil.CodeUser)
+		}
+	}
+
+	multi := errutil.Join(errutil.New("first"), errutil.WithCode(errutil.New("second"), errutil.CodeUser))
+	if got := errutil.CodeWith(multi, errutil.CodeByPrecedence()); got != errutil.CodeUser {
+		t.Errorf("CodeWith(multi) = %q, want %q", got, errutil.CodeUser)
+	}
+	if got := errutil.CodeWith(errutil.New("only default"), errutil.CodeInnermost()); got != errutil.DefaultCode {
+		t.Errorf("CodeWith(New) = %q, want %q", got, errutil.DefaultCode)
+	}
+
+	defer errutil.SetDefault(nil)
+	errutil.SetDefault(errutil.NewFactory(errutil.Config{CodeResolver: errutil.CodeHighestSeverity()}))
+
+	err = errutil.WithCode(errutil.New("bad input"), errutil.CodeUser)
+	if got := err.Error(); got != "[USER] bad input" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := errutil.HTTPStatus(err); got != http.StatusBadRequest {
+		t.Errorf("HTTPStatus() = %d", got)
+	}
+}
+
+func TestCodeResolverConfig(t *testing.T) {
+	defer errutil.SetDefault(nil)
+	errutil.SetDefault(errutil.NewFactory(errutil.Config{CodeResolver: errutil.CodeHighestSeverity()}))
+
+	err := errutil.WithCode(errutil.NewWithCode(errutil.CodePanic, "nil map"), errutil.CodeUser)
+
+	if got := errutil.Code(err); got != errutil.CodePanic {
+		t.Errorf("Code() = %q", got)
+	}
+	if got := err.Error(); got != "[PANIC] nil map" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := errutil.HTTPStatus(err); got != http.StatusInternalServerError {
+		t.Errorf("HTTPStatus() = %d", got)
+	}
+	if got := errutil.GRPCStatus(err); got != errutil.GRPCInternal {
+		t.Errorf("GRPCStatus() = %d", got)
+	}
+	if got := errutil.SlogValue(err, false).Group()[1].Value.String(); got != errutil.CodePanic {
+		t.Errorf("slog code = %q", got)
+	}
+
+	// Коды веток мультиошибки объединяются по Config.CodePrecedence
+	multi := errutil.Join(errutil.NewWithCode(errutil.CodeUser), errutil.WithCode(errutil.NewWithCode(errutil.CodeCritical), errutil.CodeUser))
+	if got := errutil.Code(multi); got != errutil.CodeCritical {
+		t.Errorf("Code(multi) = %q", got)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Интеграция с log/slog: представление ошибки в виде группы атрибутов
+// и обработчик, раскрывающий ошибки в записях лога
+
+package errutil
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// SlogValue - представление ошибки в виде группы атрибутов slog:
+// текст, код, сообщение для пользователя, dev-сообщения и, при withStack, стек вызовов
+func SlogValue(err error, withStack bool) slog.Value {
+	if err == nil {
+		return slog.Value{}
+	}
+
+	attrs := make([]slog.Attr, 0, 6)
+	attrs = append(attrs,
+		slog.String("error", err.Error()),
+		slog.String("code", Code(err)),
+	)
+
+	if msg := Message(err); msg != "" {
+		attrs = append(attrs, slog.String("message", msg))
+	}
+
+	if dev := DevMessages(err); len(dev) > 0 {
+		attrs = append(attrs, slog.Any("dev", dev))
+	}
+
+	if fields := Fields(err); len(fields) > 0 {
+		fieldAttrs := make([]any, 0, len(fields))
+		for _, key := range slices.Sorted(maps.Keys(fields)) {
+			fieldAttrs = append(fieldAttrs, slog.Any(key, fields[key]))
+		}
+		attrs = append(attrs, slog.Group("fields", fieldAttrs...))
+	}
+
+	if withStack {
+		if frames := StackTrace(err); len(frames) > 0 {
+			attrs = append(attrs, slog.Any("stack", stackStrings(frames)))
+		}
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// stackStrings - краткое представление фреймов стека для лога
+func stackStrings(frames []StackFrame) []string {
+	result := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		result = append(result, fmt.Sprintf("%s.%s %s:%d", frame.Package, frame.Function, frame.File, frame.LineNumber))
+	}
+
+	return result
+}
+
+// SeverityLogLevel - получение уровня записи slog по уровню серьёзности ошибки
+func SeverityLogLevel(severity SeverityLevel) slog.Level {
+	switch severity {
+	case SeverityDebug:
+		return slog.LevelDebug
+	case SeverityInfo:
+		return slog.LevelInfo
+	case SeverityWarning:
+		return slog.LevelWarn
+	case SeverityCritical:
+		return slog.LevelError + 4
+	}
+
+	return slog.LevelError
+}
+
+/*
+----------
+*/
+
+// SlogHandlerOptions - настройки обработчика SlogHandler
+type SlogHandlerOptions struct {
+	// Коды ошибок, для которых в запись добавляется стек вызовов.
+	// По умолчанию CodePanic и CodeCritical.
+	StackCodes []string
+
+	// Определение уровня записи по ошибке.
+	// По умолчанию уровень определяется по уровню серьёзности кода из реестра.
+	Level func(err error) slog.Level
+}
+
+// SlogHandler - обработчик slog, раскрывающий атрибуты-ошибки в группы атрибутов
+// и определяющий уровень записи по коду ошибки.
+// Если в записи несколько ошибок, используется наибольший уровень.
+type SlogHandler struct {
+	next slog.Handler
+	opts SlogHandlerOptions
+}
+
+// NewSlogHandler - конструктор обработчика, передающего записи в next
+func NewSlogHandler(next slog.Handler, opts *SlogHandlerOptions) *SlogHandler {
+	h := &SlogHandler{
+		next: next,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	if h.opts.StackCodes == nil {
+		h.opts.StackCodes = []string{CodePanic, CodeCritical}
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = func(err error) slog.Level {
+			return SeverityLogLevel(Severity(err))
+		}
+	}
+
+	return h
+}
+
+// Enabled - проверка уровня записи во вложенном обработчике
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle - раскрытие ошибок в записи и передача её во вложенный обработчик
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var level slog.Level
+	var found bool
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		a = h.expand(a, func(err error) {
+			lvl := h.opts.Level(err)
+			if !found || lvl > level {
+				level = lvl
+			}
+			found = true
+		})
+		attrs = append(attrs, a)
+
+		return true
+	})
+
+	if !found {
+		return h.next.Handle(ctx, r)
+	}
+
+	if !h.next.Enabled(ctx, level) {
+		return nil
+	}
+
+	record := slog.NewRecord(r.Time, level, r.Message, r.PC)
+	record.AddAttrs(attrs...)
+
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs - создание обработчика с дополнительными атрибутами
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	expanded := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		expanded = append(expanded, h.expand(a, nil))
+	}
+
+	return &SlogHandler{
+		next: h.next.WithAttrs(expanded),
+		opts: h.opts,
+	}
+}
+
+// WithGroup - создание обработчика с группой атрибутов
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	return &SlogHandler{
+		next: h.next.WithGroup(name),
+		opts: h.opts,
+	}
+}
+
+// expand - раскрытие атрибута-ошибки, в том числе внутри групп.
+// Для каждой найденной ошибки вызывается onError.
+func (h *SlogHandler) expand(a slog.Attr, onError func(err error)) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindAny, slog.KindLogValuer:
+		err, ok := a.Value.Any().(error)
+		if !ok || err == nil {
+			return a
+		}
+		if onError != nil {
+			onError(err)
+		}
+
+		return slog.Attr{Key: a.Key, Value: SlogValue(err, slices.Contains(h.opts.StackCodes, Code(err)))}
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]slog.Attr, 0, len(group))
+		for _, ga := range group {
+			attrs = append(attrs, h.expand(ga, onError))
+		}
+
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+	}
+
+	return a
+}
@@ -0,0 +1,81 @@
+package errutil_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestSlogValue(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	err := errutil.WithMessage(errutil.NewWithCode(errutil.CodeUser, "invalid id"), "Неверный идентификатор")
+	logger.Info("request failed", "err", err)
+
+	var record struct {
+		Err struct {
+			Error   string   `json:"error"`
+			Code    string   `json:"code"`
+			Message string   `json:"message"`
+			Dev     []string `json:"dev"`
+			Stack   []string `json:"stack"`
+		} `json:"err"`
+	}
+	if e := json.Unmarshal(buf.Bytes(), &record); e != nil {
+		t.Fatal(e)
+	}
+
+	if record.Err.Code != errutil.CodeUser || record.Err.Message != "Неверный идентификатор" ||
+		len(record.Err.Dev) != 1 || record.Err.Error != err.Error() {
+		t.Errorf("record = %s", buf.String())
+	}
+	if record.Err.Stack != nil {
+		t.Error("LogValue must not contain stack")
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := slog.New(errutil.NewSlogHandler(slog.NewJSONHandler(&buf, nil), nil))
+
+	var record struct {
+		Level string `json:"level"`
+		Req   struct {
+			Err struct {
+				Code  string   `json:"code"`
+				Stack []string `json:"stack"`
+			} `json:"err"`
+		} `json:"req"`
+	}
+
+	logger.Info("failed", slog.Group("req", "err", errutil.New("db timeout")))
+	if e := json.Unmarshal(buf.Bytes(), &record); e != nil {
+		t.Fatal(e)
+	}
+	if record.Level != "ERROR" || record.Req.Err.Code != errutil.CodeCritical || stackEnabled && len(record.Req.Err.Stack) == 0 {
+		t.Errorf("record = %s", buf.String())
+	}
+
+	buf.Reset()
+	record.Req.Err.Stack = nil
+	logger.Error("failed", slog.Group("req", "err", errutil.NewWithCode(errutil.CodeUser, "invalid id")))
+	if e := json.Unmarshal(buf.Bytes(), &record); e != nil {
+		t.Fatal(e)
+	}
+	if record.Level != "WARN" || record.Req.Err.Code != errutil.CodeUser || record.Req.Err.Stack != nil {
+		t.Errorf("record = %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Info("ok", "id", 1)
+	if e := json.Unmarshal(buf.Bytes(), &record); e != nil {
+		t.Fatal(e)
+	}
+	if record.Level != "INFO" {
+		t.Errorf("record = %s", buf.String())
+	}
+}
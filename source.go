@@ -0,0 +1,163 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Кэш исходного кода для фреймов стека и получение контекста строки
+
+package errutil
+
+import (
+	"container/list"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SourceContext - строка исходного кода фрейма с окружающими строками
+type SourceContext struct {
+	// Строки перед строкой фрейма
+	Pre []string `json:"pre,omitempty"`
+
+	// Строка фрейма
+	Line string `json:"line"`
+
+	// Строки после строки фрейма
+	Post []string `json:"post,omitempty"`
+}
+
+// SetSourceFS - использование fs.FS (например, embed.FS) как источника исходного кода.
+// Из пути файла фрейма удаляется префикс root, остаток используется как путь в fsys.
+// Если файл не найден в fsys, он читается из файловой системы.
+// При fsys == nil используется только файловая система. Кэш исходного кода очищается.
+func SetSourceFS(fsys fs.FS, root string) {
+	sources.mu.Lock()
+	sources.fsys = fsys
+	sources.root = root
+	sources.reset()
+	sources.mu.Unlock()
+}
+
+// ClearSourceCache - очистка кэша исходного кода
+func ClearSourceCache() {
+	sources.mu.Lock()
+	sources.reset()
+	sources.mu.Unlock()
+}
+
+// SourceContext возвращает строку исходного кода фрейма и до n строк до и после неё.
+// Строки возвращаются с исходными отступами, отрицательное n считается нулём.
+func (frame *StackFrame) SourceContext(n int) (SourceContext, error) {
+	if frame.LineNumber <= 0 {
+		return SourceContext{Line: "..."}, nil
+	}
+
+	lines, err := sources.lines(frame.File)
+	if err != nil {
+		return SourceContext{}, err
+	}
+
+	n = max(n, 0)
+	i := frame.LineNumber - 1
+	if i >= len(lines) {
+		return SourceContext{Line: "..."}, nil
+	}
+
+	return SourceContext{
+		Pre:  slices.Clone(lines[max(i-n, 0):i]),
+		Line: lines[i],
+		Post: slices.Clone(lines[i+1 : min(i+1+n, len(lines))]),
+	}, nil
+}
+
+/*
+----------
+*/
+
+// sources - кэш исходного кода пакета
+var sources = &sourceCache{}
+
+// sourceCache - потокобезопасный кэш строк исходных файлов с вытеснением
+// давно не использованных файлов (LRU)
+type sourceCache struct {
+	mu    sync.Mutex
+	fsys  fs.FS
+	root  string
+	files map[string]*list.Element
+	order *list.List
+
+	// Номер поколения кэша, увеличивается при каждой очистке:
+	// файл, прочитанный до очистки, не добавляется в кэш
+	generation uint64
+}
+
+// sourceFile - строки исходного файла или ошибка его чтения
+type sourceFile struct {
+	name  string
+	lines []string
+	err   error
+}
+
+// reset - очистка кэша, вызывается под блокировкой
+func (c *sourceCache) reset() {
+	c.files = make(map[string]*list.Element)
+	c.order = list.New()
+	c.generation++
+}
+
+// lines - получение строк исходного файла из кэша или с чтением файла
+func (c *sourceCache) lines(name string) ([]string, error) {
+	c.mu.Lock()
+	if c.files == nil {
+		c.reset()
+	}
+	if el, ok := c.files[name]; ok {
+		c.order.MoveToFront(el)
+		file := el.Value.(*sourceFile)
+		c.mu.Unlock()
+		return file.lines, file.err
+	}
+	fsys, root, generation := c.fsys, c.root, c.generation
+	c.mu.Unlock()
+
+	file := &sourceFile{name: name}
+	file.lines, file.err = readSource(fsys, root, name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.files[name]; !ok && c.generation == generation {
+		c.files[name] = c.order.PushFront(file)
+		for c.order.Len() > max(Default().sourceCacheSize(), 1) {
+			el := c.order.Back()
+			c.order.Remove(el)
+			delete(c.files, el.Value.(*sourceFile).name)
+		}
+	}
+
+	return file.lines, file.err
+}
+
+// readSource - чтение исходного файла из fsys или файловой системы и разбиение на строки
+func readSource(fsys fs.FS, root string, name string) ([]string, error) {
+	var data []byte
+	var err error
+
+	if fsys != nil {
+		data, err = fs.ReadFile(fsys, strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(name, root)), "/"))
+	}
+	if fsys == nil || err != nil {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,77 @@
+package errutil_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestSourceContext(t *testing.T) {
+	requireStack(t)
+
+	frames := errutil.StackTrace(errutil.New("test"))
+	frame := frames[len(frames)-1]
+
+	source, err := frame.SourceContext(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Line != "\tframes := errutil.StackTrace(errutil.New(\"test\"))" {
+		t.Errorf("Line = %q", source.Line)
+	}
+	if !slices.Equal(source.Pre, []string{"\trequireStack(t)", ""}) {
+		t.Errorf("Pre = %q", source.Pre)
+	}
+	if !slices.Equal(source.Post, []string{"\tframe := frames[len(frames)-1]", ""}) {
+		t.Errorf("Post = %q", source.Post)
+	}
+
+	if source, err = frame.SourceContext(-1); err != nil || len(source.Pre) != 0 || len(source.Post) != 0 || source.Line == "" {
+		t.Errorf("SourceContext(-1) = %q, %v", source, err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if line, err := frame.SourceLine(); err != nil || line != "frames := errutil.StackTrace(errutil.New(\"test\"))" {
+				t.Errorf("SourceLine() = %q, %v", line, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSourceFS(t *testing.T) {
+	requireStack(t)
+
+	errutil.SetSourceFS(fstest.MapFS{
+		"app/main.go": {Data: []byte("package main\r\n\r\nfunc main() {\r\n\tpanic(\"boom\")\r\n}\r\n")},
+	}, "/build/src")
+	defer errutil.SetSourceFS(nil, "")
+
+	frame := errutil.StackFrame{File: "/build/src/app/main.go", LineNumber: 4, PC: 1}
+
+	source, err := frame.SourceContext(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Line != "\tpanic(\"boom\")" || !slices.Equal(source.Pre, []string{"func main() {"}) ||
+		!slices.Equal(source.Post, []string{"}"}) {
+		t.Errorf("SourceContext() = %q", source)
+	}
+
+	frame.LineNumber = 100
+	if line, err := frame.SourceLine(); err != nil || line != "..." {
+		t.Errorf("SourceLine() = %q, %v", line, err)
+	}
+
+	frame.File = "/build/src/app/missing.go"
+	if _, err = frame.SourceLine(); err == nil {
+		t.Error("SourceLine() of missing file returned no error")
+	}
+}
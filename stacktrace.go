@@ -4,20 +4,17 @@
 package errutil
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"go/build"
-	"os"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 )
 
-// MaxStackTraceDepth - максимальная глубина стека
-var MaxStackTraceDepth = 50
-
-// StackTraceAppPrefix - префикс модуля, по которому определяется свойство InApp фрейма
-var StackTraceAppPrefix = "github.com/kontora13-go"
+// MaxStackTraceDepth - максимальная глубина стека, если не задана Config.MaxStackTraceDepth
+const MaxStackTraceDepth = 50
 
 var goRoot = strings.ReplaceAll(build.Default.GOROOT, "\\", "/")
 
@@ -35,8 +32,12 @@ type StackFrame struct {
 	// Package, содержащий эту функцию
 	Package string `json:"package,omitempty"`
 
+	// Признак вызова внутри приложения
 	InApp bool `json:"in_app,omitempty"`
 
+	// Происхождение кода: стандартная библиотека, сторонний код или приложение
+	Kind FrameKind `json:"kind,omitempty"`
+
 	PC uintptr `json:"pc,omitempty"`
 }
 
@@ -61,75 +62,131 @@ func (frame *StackFrame) IsEmpty() bool {
 	return frame.PC == 0
 }
 
-// SourceLine возвращает строку кода из исходного файла
+// SourceLine возвращает строку кода из исходного файла.
+// Исходные файлы читаются через кэш, см. SetSourceFS и Config.SourceCacheSize.
 func (frame *StackFrame) SourceLine() (string, error) {
-	if frame.LineNumber <= 0 {
-		return "...", nil
-	}
-
-	file, err := os.Open(frame.File)
+	source, err := frame.SourceContext(0)
 	if err != nil {
 		return "", err
 	}
-	defer func() {
-		_ = file.Close()
-	}()
 
-	scanner := bufio.NewScanner(file)
-	currentLine := 1
-	for scanner.Scan() {
-		if currentLine == frame.LineNumber {
-			return string(bytes.Trim(scanner.Bytes(), " \t")), nil
-		}
-		currentLine++
+	return strings.Trim(source.Line, " \t"), nil
+}
+
+// stackElided - отметка об отброшенных внешних фреймах стека, аналогичная runtime
+const stackElided = "...additional frames elided...\n"
+
+// NewStackTrace создает стектрейс []StackFrame с использованием runtime.Callers.
+// Фреймы упорядочены от самого внешнего вызова к месту вызова NewStackTrace,
+// встроенные (inlined) функции представлены отдельными фреймами.
+func NewStackTrace(skip int) []StackFrame {
+	f := Default()
+
+	return f.newStack(callers(skip+1, f.maxStackTraceDepth())).Frames()
+}
+
+// stack - стек вызовов, хранящий только счётчики команд.
+// Фреймы StackFrame строятся при первом обращении и кэшируются.
+type stack struct {
+	pcs       []uintptr
+	truncated bool
+	appPrefix string
+	once      sync.Once
+	frames    []StackFrame
+}
+
+// newStack создаёт стек вызовов из счётчиков команд.
+// Признак truncated означает, что стек обрезан по максимальной глубине.
+func newStack(pcs []uintptr, truncated bool) *stack {
+	if len(pcs) == 0 {
+		return nil
 	}
-	if err = scanner.Err(); err != nil {
-		return "", err
+
+	return &stack{pcs: pcs, truncated: truncated}
+}
+
+// stackFromFrames создаёт стек вызовов из готовых фреймов, например, восстановленных из JSON
+func stackFromFrames(frames []StackFrame, truncated bool) *stack {
+	if len(frames) == 0 {
+		return nil
 	}
 
-	return "...", nil
+	s := &stack{truncated: truncated}
+	s.once.Do(func() {
+		s.frames = frames
+	})
+
+	return s
 }
 
-// NewStackTrace создает стектрейс []StackFrame с использованием runtime.Callers.
-func NewStackTrace(skip int) []StackFrame {
-	pcs := make([]uintptr, MaxStackTraceDepth)
-	n := runtime.Callers(skip+1, pcs)
+// Truncated возвращает признак того, что стек обрезан по MaxStackTraceDepth
+// и самые внешние вызовы в нём отсутствуют
+func (s *stack) Truncated() bool {
+	return s != nil && s.truncated
+}
+
+// String возвращает стек, отформатированный так же, как это делает go в runtime/debug.Stack()
+func (s *stack) String() string {
+	buf := bytes.Buffer{}
 
-	if n == 0 {
+	if s.Truncated() {
+		buf.WriteString(stackElided)
+	}
+	for _, frame := range s.Frames() {
+		buf.WriteString(frame.String())
+	}
+
+	return buf.String()
+}
+
+// Frames возвращает фреймы стека, при первом вызове выполняя их построение
+func (s *stack) Frames() []StackFrame {
+	if s == nil {
 		return nil
 	}
 
-	runtimeFrames := extractFrames(pcs[:n])
-	return createFrames(runtimeFrames)
+	s.once.Do(func() {
+		s.frames = createFrames(extractFrames(s.pcs), s.appPrefix)
+	})
+
+	return s.frames
 }
 
-// extractFrames выполняет распаковку слайса uintptr в слайс runtime.Frame
+// extractFrames выполняет распаковку слайса uintptr в слайс runtime.Frame.
+// Один счётчик команд может соответствовать нескольким фреймам встроенных (inlined) функций,
+// поэтому сохраняются все фреймы, возвращаемые runtime.CallersFrames.
+// Результат упорядочен от самого внешнего вызова к самому внутреннему.
 func extractFrames(pcs []uintptr) []runtime.Frame {
-	n := len(pcs)
-	var frames = make([]runtime.Frame, n)
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	frames := make([]runtime.Frame, 0, len(pcs))
 	callersFrames := runtime.CallersFrames(pcs)
 
-	i := n - 1
 	for {
 		callerFrame, more := callersFrames.Next()
-		frames[i] = callerFrame
-		i--
+		frames = append(frames, callerFrame)
 
-		if !more || i == 0 {
+		if !more {
 			break
 		}
 	}
 
+	slices.Reverse(frames)
+
 	return frames
 }
 
 // createFrames создаёт слайс StackFrame, отфильтровывая лишние фреймы
-// например, runtime и testing
-func createFrames(frames []runtime.Frame) []StackFrame {
+// например, runtime и testing, согласно настройкам SetFrameFilter.
+// appPrefix - префикс модуля приложения из Config.StackTraceAppPrefix.
+func createFrames(frames []runtime.Frame, appPrefix string) []StackFrame {
 	if len(frames) == 0 {
 		return nil
 	}
 
+	filter := currentFrameFilter()
 	result := make([]StackFrame, 0, len(frames))
 
 	for _, frame := range frames {
@@ -139,16 +196,21 @@ func createFrames(frames []runtime.Frame) []StackFrame {
 			pkg, function = splitPackageAndFunction(function)
 		}
 
-		if !shouldSkipFrame(pkg) {
-			result = append(result, newFrame(pkg, function, frame.File, frame.Line, frame.PC))
+		if !shouldSkipFrame(filter, pkg) {
+			result = append(result, newFrame(filter, appPrefix, pkg, function, frame.File, frame.Line, frame.PC))
 		}
 	}
 
+	// Отбрасываем фреймы пакетов-обёрток в конце стека
+	for len(result) > 0 && filter.isHelper(&result[len(result)-1]) {
+		result = result[:len(result)-1]
+	}
+
 	return result
 }
 
 // newFrame создаёт объект фрейма стека
-func newFrame(pkg string, function string, file string, line int, pc uintptr) StackFrame {
+func newFrame(filter *FrameFilter, appPrefix string, pkg string, function string, file string, line int, pc uintptr) StackFrame {
 	frame := StackFrame{
 		LineNumber: line,
 		Package:    pkg,
@@ -161,7 +223,7 @@ func newFrame(pkg string, function string, file string, line int, pc uintptr) St
 		frame.File = "unknown"
 	}
 
-	setInAppFrame(&frame)
+	setInAppFrame(filter, appPrefix, &frame)
 
 	return frame
 }
@@ -200,30 +262,26 @@ func packageName(name string) string {
 }
 
 // shouldSkipFrame проверяет нужно ли пропустить текущий фрейм по имени пакета.
-func shouldSkipFrame(pkg string) bool {
-	// Пропускаем внутренние пакеты Go
-	if pkg == "runtime" || pkg == "testing" {
-		return true
-	}
-
+func shouldSkipFrame(filter *FrameFilter, pkg string) bool {
 	// Пропускаем внутренние фреймы пакета errutil, за исключением _test (для тестирования).
 	if strings.HasPrefix(pkg, "github.com/kontora13-go/errutil") &&
 		!strings.HasSuffix(pkg, "_test") {
 		return true
 	}
 
-	return false
+	// Пропускаем исключённые пакеты, по умолчанию внутренние пакеты Go runtime и testing
+	return filter.isExcluded(pkg)
 }
 
-// setInAppFrame устанавливает признак вызова внутри приложения
-func setInAppFrame(frame *StackFrame) {
-	if strings.HasPrefix(frame.File, goRoot) ||
-		strings.Contains(frame.Package, "vendor") ||
-		strings.Contains(frame.Package, "third_party") {
-		frame.InApp = false
-	} else if strings.HasPrefix(frame.Package, StackTraceAppPrefix) {
-		frame.InApp = true
+// setInAppFrame устанавливает происхождение фрейма и признак вызова внутри приложения
+func setInAppFrame(filter *FrameFilter, appPrefix string, frame *StackFrame) {
+	if filter.isAppPackage(frame.Package, appPrefix) {
+		frame.Kind = FrameApp
+	} else if isStdlibPackage(frame.Package, frame.File) {
+		frame.Kind = FrameStdlib
 	} else {
-		frame.InApp = false
+		frame.Kind = FrameThirdParty
 	}
+
+	frame.InApp = frame.Kind == FrameApp
 }
@@ -0,0 +1,159 @@
+package errutil_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+func TestStackTraceLazy(t *testing.T) {
+	requireStack(t)
+
+	err := errutil.New("test")
+
+	wg := sync.WaitGroup{}
+	results := make([][]errutil.StackFrame, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = errutil.StackTrace(err)
+		}()
+	}
+	wg.Wait()
+
+	if len(results[0]) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+	for _, frames := range results[1:] {
+		if &frames[0] != &results[0][0] {
+			t.Error("StackTrace() frames are not cached")
+		}
+	}
+	if errutil.Stack(err) == "" {
+		t.Error("Stack() is empty")
+	}
+}
+
+type frameLine struct {
+	function string
+	line     int
+}
+
+//go:noinline
+func noinlineNew() error {
+	return errutil.New("inlined")
+}
+
+// inlinedNew - небольшая функция, которую компилятор встраивает в место вызова
+func inlinedNew() error {
+	return noinlineNew()
+}
+
+//go:noinline
+func outerNew(lines *[]frameLine) error {
+	*lines = append(*lines,
+		frameLine{"outerNew", currentLine() + 4},
+		frameLine{"inlinedNew", funcLine(inlinedNew) + 1},
+		frameLine{"noinlineNew", funcLine(noinlineNew) + 1},
+	)
+	return inlinedNew()
+}
+
+func funcLine(f func() error) int {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	_, line := fn.FileLine(fn.Entry())
+	return line
+}
+
+// requireStack - пропуск теста, проверяющего стек вызовов, в сборке с тегом errutil_nostack
+func requireStack(t *testing.T) {
+	t.Helper()
+	if !stackEnabled {
+		t.Skip("stack capture is disabled by errutil_nostack")
+	}
+}
+
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestStackTraceFrames(t *testing.T) {
+	requireStack(t)
+
+	var lines []frameLine
+
+	lines = append(lines, frameLine{"TestStackTraceFrames", currentLine() + 1})
+	err := outerNew(&lines)
+
+	frames := errutil.StackTrace(err)
+	if len(frames) < len(lines) {
+		t.Fatalf("StackTrace() = %v", frames)
+	}
+
+	for i, frame := range frames {
+		if frame.IsEmpty() || frame.Function == "" {
+			t.Errorf("frame #%d is empty: %+v", i, frame)
+		}
+	}
+
+	_, file, _, _ := runtime.Caller(0)
+	frames = frames[len(frames)-len(lines):]
+	for i, want := range lines {
+		got := frames[i]
+		if got.File != file || got.Function != want.function || got.LineNumber != want.line {
+			t.Errorf("frame #%d = %s:%d %s, want %s:%d %s",
+				i, got.File, got.LineNumber, got.Function, file, want.line, want.function)
+		}
+		if got.Package != "github.com/kontora13-go/errutil_test" {
+			t.Errorf("frame #%d package = %q", i, got.Package)
+		}
+	}
+
+	if errutil.StackTruncated(err) {
+		t.Error("StackTruncated() = true for shallow stack")
+	}
+}
+
+func TestStackTraceTruncated(t *testing.T) {
+	requireStack(t)
+
+	defer errutil.SetDefault(nil)
+	errutil.SetDefault(errutil.NewFactory(errutil.Config{MaxStackTraceDepth: 5}))
+
+	line := currentLine() + 1
+	err := newErrorStackWithDepth(nil, 10)
+
+	frames := errutil.StackTrace(err)
+	if len(frames) == 0 || len(frames) > 5 {
+		t.Fatalf("StackTrace() has %d frames, want 1..5", len(frames))
+	}
+	if last := frames[len(frames)-1]; last.Function != "newErrorStackWithDepth" || last.LineNumber == line {
+		t.Errorf("innermost frame = %s:%d %s", last.File, last.LineNumber, last.Function)
+	}
+
+	if !errutil.StackTruncated(err) {
+		t.Error("StackTruncated() = false")
+	}
+	if stack := errutil.Stack(err); !strings.HasPrefix(stack, "...additional frames elided...\n") {
+		t.Errorf("Stack() = %q", stack)
+	}
+
+	data, e := json.Marshal(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var decoded error
+	if e := errutil.Decode(data, &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if !errutil.StackTruncated(decoded) {
+		t.Error("decoded StackTruncated() = false")
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Преобразование цепочки ошибки: удаление стеков и сообщений для разработчика,
+// замена кодов и сообщений перед передачей ошибки за пределы сервиса
+
+package errutil
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// TransformOption - преобразование уровней цепочки для Transform,
+// создаётся функциями StripStacks, StripDevMessages, ReplaceCode, MapMessages и KeepOnlyCodes
+type TransformOption struct {
+	// Преобразование сегмента, false - сегмент удаляется из цепочки
+	apply func(s *segment) bool
+}
+
+// Transform - построение новой цепочки ошибки с уровнями, преобразованными opts.
+// Исходная сторонняя ошибка цепочки сохраняется без изменений, ветки мультиошибки
+// преобразуются по отдельности. Сторонние обёртки над ошибками пакета (fmt.Errorf("%w"),
+// errors.Join) перестраиваются: их текст заменяется текстом преобразованной цепочки.
+// errors.Is для полученной ошибки совпадает с ошибками, из которых получена исходная,
+// в том числе при удалении их уровней.
+func Transform(err error, opts ...TransformOption) error {
+	t := transformer{
+		opts:     opts,
+		index:    causerIndex{err: err},
+		maxDepth: factoryOf(err).maxChainDepth(),
+	}
+
+	return t.transform(err, 0)
+}
+
+// transformer - состояние преобразования цепочки ошибки
+type transformer struct {
+	opts     []TransformOption
+	index    causerIndex
+	maxDepth int
+}
+
+// transform - преобразование ветки цепочки: уровни собираются от внешнего к внутреннему
+// до сторонней ошибки без ошибок пакета внутри или мультиошибки, затем перестраиваются
+// от внутреннего к внешнему. Цикл и уровни глубже maxDepth сохраняются без изменений.
+func (t *transformer) transform(err error, depth int) error {
+	var layers []error
+	var result error
+	cycle := cycleDetector{}
+
+	for {
+		if v, ok := err.(viewer); ok {
+			err = v.viewNode()
+		}
+		if e, ok := err.(*node); ok {
+			layers = append(layers, e)
+			depth += len(e.segs)
+			err = e.root()
+			continue
+		}
+		if err == nil || depth >= t.maxDepth || cycle.seen(err) || !t.index.contains(err) {
+			result = err
+			break
+		}
+		depth++
+
+		next, multi := unwrapOnce(err)
+		if multi != nil {
+			errs := make([]error, len(multi))
+			for i, err := range multi {
+				errs[i] = t.transform(err, depth)
+			}
+
+			result = &multiError{errs: errs}
+			if !isMultiError(err) {
+				result = &redactedError{origin: err, err: result}
+			}
+			break
+		}
+
+		layers = append(layers, err)
+		err = next
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		if e, ok := layers[i].(*node); ok {
+			result = transformNode(e, result, t.opts)
+		} else {
+			result = &redactedError{origin: layers[i], err: result}
+		}
+	}
+
+	return result
+}
+
+// transformNode - перестроение уровней узла e над преобразованной исходной ошибкой result
+func transformNode(e *node, result error, opts []TransformOption) error {
+	var top *node
+	var dropped []*segment
+
+	for _, s := range e.segs {
+		seg := *s
+		seg.origins = append(append(dropped, s.origins...), s)
+		dropped = nil
+
+		if !transformSegment(&seg, opts) {
+			dropped = seg.origins
+			continue
+		}
+
+		top = newNode(result, seg)
+		result = top
+	}
+
+	if len(dropped) == 0 {
+		return result
+	}
+
+	// Удалённые внешние уровни сохраняются для errors.Is во внешнем оставшемся уровне,
+	// а если удалены все - в пустом уровне кода
+	if top == nil {
+		return newNode(result, segment{kind: segmentCode, origins: dropped})
+	}
+	top.seg.origins = append(top.seg.origins, dropped...)
+
+	return result
+}
+
+func transformSegment(s *segment, opts []TransformOption) bool {
+	for _, opt := range opts {
+		if opt.apply != nil && !opt.apply(s) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// StripStacks - удаление стеков вызовов, коды уровней со стеком сохраняются
+func StripStacks() TransformOption {
+	return TransformOption{apply: func(s *segment) bool {
+		s.stack = nil
+		return true
+	}}
+}
+
+// StripDevMessages - удаление сообщений для разработчика
+func StripDevMessages() TransformOption {
+	return TransformOption{apply: func(s *segment) bool {
+		return s.kind != segmentDev
+	}}
+}
+
+// ReplaceCode - замена кода from на to
+func ReplaceCode(from, to string) TransformOption {
+	return TransformOption{apply: func(s *segment) bool {
+		if s.code == from && (s.kind == segmentCode || s.kind == segmentStack) {
+			s.code = to
+			s.implicit = false
+		}
+		return true
+	}}
+}
+
+// MapMessages - замена сообщений для пользователя результатом mapping
+func MapMessages(mapping func(msg string) string) TransformOption {
+	return TransformOption{apply: func(s *segment) bool {
+		if s.kind == segmentMessage {
+			s.msg = mapping(s.msg)
+		}
+		return true
+	}}
+}
+
+// KeepOnlyCodes - удаление кодов, отсутствующих в codes.
+// Код ошибки без оставшихся кодов определяется как код по умолчанию.
+func KeepOnlyCodes(codes ...string) TransformOption {
+	return TransformOption{apply: func(s *segment) bool {
+		if s.code == "" || slices.Contains(codes, s.code) {
+			return true
+		}
+
+		s.code = ""
+		return s.kind != segmentCode
+	}}
+}
+
+/*
+----------
+*/
+
+// redactedError - сторонняя обёртка, перестроенная Transform: текст обёртки
+// заменяется текстом преобразованной цепочки err, errors.Is совпадает с исходной обёрткой
+type redactedError struct {
+	origin error
+	err    error
+}
+
+// Error - получение текстового представления ошибки
+func (e *redactedError) Error() string {
+	return e.err.Error()
+}
+
+// Format - форматирование ошибки для пакета fmt
+func (e *redactedError) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
+}
+
+// Unwrap - распаковка преобразованной цепочки
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Is - сравнение с исходной обёрткой для errors.Is
+func (e *redactedError) Is(target error) bool {
+	return reflect.ValueOf(e.origin).Comparable() && e.origin == target
+}
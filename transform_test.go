@@ -0,0 +1,141 @@
+package errutil_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+var errNotFound = errutil.NewWithCode("NOT_FOUND", "record not found")
+
+func TestTransform(t *testing.T) {
+	err := errutil.WithMessage(errutil.WithDevMessage(errutil.WithStack(io.EOF), "read config"), "config unavailable")
+	err = errutil.WithCode(err, "INTERNAL")
+
+	got := errutil.Transform(err, errutil.StripStacks(), errutil.StripDevMessages(),
+		errutil.ReplaceCode("INTERNAL", errutil.CodeCritical),
+		errutil.MapMessages(strings.ToUpper))
+
+	if errutil.Stack(got) != "" || len(errutil.StackTraces(got)) != 0 {
+		t.Error("stack is not stripped")
+	}
+	if dev := errutil.DevMessages(got); len(dev) != 1 || dev[0] != io.EOF.Error() {
+		t.Errorf("DevMessages() = %q", dev)
+	}
+	if code := errutil.Code(got); code != errutil.CodeCritical {
+		t.Errorf("Code() = %q", code)
+	}
+	if msg := errutil.Message(got); msg != "CONFIG UNAVAILABLE" {
+		t.Errorf("Message() = %q", msg)
+	}
+	if errutil.Cause(got) != io.EOF || !errors.Is(got, io.EOF) {
+		t.Errorf("Cause() = %v, want io.EOF", errutil.Cause(got))
+	}
+	if !errors.Is(got, err) {
+		t.Error("errors.Is(transformed, original) = false")
+	}
+
+	// Исходная ошибка не изменяется
+	if stackEnabled && errutil.Stack(err) == "" || errutil.Code(err) != "INTERNAL" || errutil.Message(err) != "config unavailable" {
+		t.Errorf("original changed: %v", err)
+	}
+}
+
+func TestTransformIs(t *testing.T) {
+	err := errutil.WithMessage(errNotFound, "not found")
+
+	tests := []struct {
+		name string
+		opts []errutil.TransformOption
+	}{
+		{"StripDevMessages", []errutil.TransformOption{errutil.StripDevMessages()}},
+		{"ReplaceCode", []errutil.TransformOption{errutil.ReplaceCode("NOT_FOUND", "MISSING")}},
+		{"KeepOnlyCodes", []errutil.TransformOption{errutil.KeepOnlyCodes(errutil.CodeUser), errutil.StripDevMessages()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errutil.Transform(err, tt.opts...)
+			if !errors.Is(got, errNotFound) {
+				t.Errorf("errors.Is(%v, errNotFound) = false", got)
+			}
+			if !errors.Is(errutil.Transform(got, tt.opts...), errNotFound) {
+				t.Error("errors.Is after repeated Transform = false")
+			}
+		})
+	}
+}
+
+func TestTransformKeepOnlyCodes(t *testing.T) {
+	err := errutil.WithCode(errutil.NewWithCode(errutil.CodeUser, "bad input"), "DB_TIMEOUT")
+
+	got := errutil.Transform(err, errutil.KeepOnlyCodes(errutil.CodeUser))
+	if code := errutil.Code(got); code != errutil.CodeUser {
+		t.Errorf("Code() = %q, want %q", code, errutil.CodeUser)
+	}
+
+	got = errutil.Transform(err, errutil.KeepOnlyCodes())
+	if code := errutil.Code(got); code != errutil.DefaultCode {
+		t.Errorf("Code() = %q, want %q", code, errutil.DefaultCode)
+	}
+}
+
+func TestTransformMulti(t *testing.T) {
+	err := errutil.Join(errutil.New("first"), errutil.WithDevMessage(io.EOF, "second"), errors.New("foreign"))
+
+	got := errutil.Transform(err, errutil.StripStacks(), errutil.StripDevMessages())
+	if len(errutil.StackTraces(got)) != 0 {
+		t.Error("stack is not stripped")
+	}
+	if !errors.Is(got, io.EOF) {
+		t.Error("errors.Is(io.EOF) = false")
+	}
+	if errutil.Transform(nil) != nil {
+		t.Error("Transform(nil) != nil")
+	}
+	if got := errutil.Transform(errNotFound, errutil.TransformOption{}); errutil.Code(got) != "NOT_FOUND" || errutil.DevMessage(got) != "record not found" {
+		t.Errorf("Transform(zero option) = %v", got)
+	}
+}
+
+func TestTransformForeignWrappers(t *testing.T) {
+	inner := errutil.WithDevMessage(errutil.NewWithCode("DB", "SELECT secret"), "query failed")
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"Errorf", fmt.Errorf("load user SELECT secret: %w", inner)},
+		{"Join", errors.Join(fmt.Errorf("SELECT secret: %w", inner), io.EOF)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errutil.Transform(tt.err, errutil.StripStacks(), errutil.StripDevMessages())
+
+			if strings.Contains(got.Error(), "SELECT secret") || strings.Contains(errutil.DevMessage(got), "SELECT secret") {
+				t.Errorf("dev text is not stripped: %q", got)
+			}
+			if text := fmt.Sprintf("%+v", errutil.WithMessage(got, "msg")); strings.Contains(text, "SELECT secret") {
+				t.Errorf("%%+v contains dev text:\n%s", text)
+			}
+			if len(errutil.StackTraces(got)) != 0 {
+				t.Error("stack is not stripped")
+			}
+			if code := errutil.Code(got); code != "DB" {
+				t.Errorf("Code() = %q, want %q", code, "DB")
+			}
+			if !errors.Is(got, inner) || !errors.Is(got, tt.err) {
+				t.Error("errors.Is(transformed, original) = false")
+			}
+		})
+	}
+
+	if got := errutil.Transform(io.EOF, errutil.StripDevMessages()); got != io.EOF {
+		t.Errorf("Transform(io.EOF) = %v", got)
+	}
+}
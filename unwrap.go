@@ -2,33 +2,188 @@ package errutil
 
 import (
 	"fmt"
+	"maps"
+	"reflect"
 	"slices"
 	"strings"
 )
 
-type causer interface {
+// Causer - ошибка, оборачивающая исходную ошибку (github.com/pkg/errors и ошибки пакета)
+type Causer interface {
 	Cause() error
 }
 
-type coder interface {
+// Coder - ошибка с кодом
+type Coder interface {
 	Code() string
 }
 
-type messager interface {
+// Messager - ошибка с сообщением для пользователя
+type Messager interface {
 	Message() string
 }
 
-type devMessager interface {
+// DevMessager - ошибка с сообщениями для разработчика
+type DevMessager interface {
 	DevMessage() string
 	DevMessages() []string
 }
 
-type tracer interface {
+// Tracer - ошибка со стеком вызовов
+type Tracer interface {
 	Stack() string
 	StackTrace() []StackFrame
 }
 
+// Fielder - ошибка с полями контекста
+type Fielder interface {
+	Fields() map[string]any
+}
+
+// unwrapOnce - получение вложенных ошибок следующего уровня.
+// Поддерживает Cause() error, Unwrap() error и Unwrap() []error (errors.Join).
+// Для одиночной цепочки заполняется next, для мультиошибки - multi.
+func unwrapOnce(err error) (next error, multi []error) {
+	switch e := err.(type) {
+	case Causer:
+		return e.Cause(), nil
+	case interface{ Unwrap() error }:
+		return e.Unwrap(), nil
+	case interface{ Unwrap() []error }:
+		return nil, e.Unwrap()
+	}
+
+	return nil, nil
+}
+
+// containsCauser - проверка наличия в цепочке ошибок врапперов пакета errutil
+func containsCauser(err error) bool {
+	found := false
+
+	walk(err, func(l *layer) walkAction {
+		if l.truncated != truncationNone {
+			return walkNext
+		}
+		if _, ok := l.err.(Causer); ok || l.seg != nil {
+			found = true
+			return walkStop
+		}
+
+		return walkNext
+	}, nil)
+
+	return found
+}
+
+// causerIndex - сторонние ошибки цепочки err, содержащие врапперы пакета errutil.
+// Строится за один обход при первом обращении, чтобы проверка каждого уровня
+// не требовала отдельного обхода оставшейся части цепочки.
+type causerIndex struct {
+	err   error
+	found map[error]struct{}
+}
+
+// contains - проверка наличия врапперов errutil в ошибке err уровня цепочки,
+// результат совпадает с containsCauser(err)
+func (c *causerIndex) contains(err error) bool {
+	if _, ok := err.(Causer); ok {
+		return true
+	}
+	if !reflect.ValueOf(err).Comparable() {
+		return containsCauser(err)
+	}
+
+	if c.found == nil {
+		c.build()
+	}
+	_, ok := c.found[err]
+
+	return ok
+}
+
+// build - обход цепочки с пометкой сторонних ошибок, ниже которых встречен враппер errutil.
+// Сторонние ошибки текущего пути хранятся в path, первые marked из них уже помечены,
+// отметки marks восстанавливают путь при завершении ветки мультиошибки.
+func (c *causerIndex) build() {
+	c.found = make(map[error]struct{})
+
+	var path []error
+	var marks []int
+	marked := 0
+
+	walk(c.err, func(l *layer) walkAction {
+		if l.truncated != truncationNone {
+			return walkNext
+		}
+		if l.seg == nil && reflect.ValueOf(l.err).Comparable() {
+			path = append(path, l.err)
+		}
+		if _, ok := l.err.(Causer); ok || l.seg != nil {
+			for _, e := range path[marked:] {
+				c.found[e] = struct{}{}
+			}
+			marked = len(path)
+		}
+
+		return walkNext
+	}, func(e walkEvent, _ error) {
+		switch e {
+		case walkBranchStart:
+			marks = append(marks, len(path))
+		case walkBranchEnd:
+			path = path[:marks[len(marks)-1]]
+			marks = marks[:len(marks)-1]
+			marked = min(marked, len(path))
+		}
+	})
+}
+
+// findCode - определение кода ошибки по стратегии Config.CodeResolver
+func findCode(err error) (string, bool) {
+	f := factoryOf(err)
+
+	return resolveCode(err, f.codeResolver(), f.codePrecedence())
+}
+
+// isSameCode - проверка, что target является ошибкой errutil с явно заданным кодом code.
+// Код по умолчанию, подставленный New, не учитывается.
+func isSameCode(code string, target error) bool {
+	if code == "" || !containsCauser(target) {
+		return false
+	}
+
+	if targetCode, ok := findCode(target); !ok || targetCode != code {
+		return false
+	}
+
+	explicit := false
+	walk(target, func(l *layer) walkAction {
+		switch {
+		case l.truncated != truncationNone:
+		case l.seg != nil:
+			explicit = l.seg.code == code && !l.seg.implicit
+		case l.multi == nil:
+			if e, ok := l.err.(Coder); ok {
+				explicit = e.Code() == code
+			}
+		}
+
+		if explicit {
+			return walkStop
+		}
+
+		return walkNext
+	}, nil)
+
+	return explicit
+}
+
 func errorString(err error) string {
+	return factoryOf(err).render(err)
+}
+
+// renderError - текстовое представление ошибки по умолчанию
+func renderError(err error) string {
 	var e, v string
 
 	v = DevMessage(err)
@@ -53,103 +208,175 @@ func errorString(err error) string {
 	return e
 }
 
+// Cause - получение исходной ошибки цепочки.
+// Для мультиошибки (errors.Join) исходной считается сама мультиошибка.
 func Cause(err error) error {
-	if err == nil {
-		return nil
-	}
+	cause := err
+
+	walk(err, func(l *layer) walkAction {
+		if l.seg != nil {
+			if e := l.node(); l.index == 0 && e.root() == nil {
+				cause = e.prefix(1)
+			}
+			return walkNext
+		}
 
-	cause, ok := err.(causer)
-	if !ok {
-		return err
-	}
+		cause = l.err
+		if l.multi != nil {
+			return walkStop
+		}
 
-	if cause.Cause() == nil {
-		return err
-	}
+		return walkNext
+	}, nil)
 
-	return Cause(cause.Cause())
+	return cause
 }
 
 func Code(err error) string {
-	if err == nil {
-		return DefaultCode
+	if code, ok := findCode(err); ok {
+		return code
 	}
 
-	e, ok := err.(coder)
-	if ok && e.Code() != "" {
-		return e.Code()
-	}
+	return factoryOf(err).defaultCode()
+}
 
-	cause, ok := err.(causer)
-	if !ok {
-		return DefaultCode
-	}
+// Codes - получение всех явно заданных кодов цепочки ошибок без повторов,
+// от внешнего уровня к внутреннему, включая ветки мультиошибок.
+// Код по умолчанию, подставленный New, не учитывается.
+func Codes(err error) []string {
+	var codes []string
+
+	walk(err, func(l *layer) walkAction {
+		code := ""
+		switch {
+		case l.truncated != truncationNone:
+		case l.seg != nil:
+			if !l.seg.implicit {
+				code = l.seg.code
+			}
+		case l.multi == nil:
+			if e, ok := l.err.(Coder); ok {
+				code = e.Code()
+			}
+		}
+
+		if code != "" && !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+
+		return walkNext
+	}, nil)
 
-	return Code(cause.Cause())
+	return codes
 }
 
-func Stack(err error) string {
-	if err == nil {
-		return ""
-	}
+// firstStack - поиск первого стека вызовов в цепочке.
+// Первый уровень со стеком в ветке определяет результат: если его стек пуст,
+// поиск продолжается только в следующих ветках мультиошибки.
+func firstStack(err error, fromSegment func(s *stack) bool, fromTracer func(t Tracer) bool) {
+	walk(err, func(l *layer) walkAction {
+		var found bool
+
+		switch {
+		case l.truncated != truncationNone:
+			return walkNext
+		case l.seg != nil:
+			if l.seg.kind != segmentStack {
+				return walkNext
+			}
+			found = fromSegment(l.seg.stack)
+		default:
+			t, ok := l.err.(Tracer)
+			if !ok {
+				return walkNext
+			}
+			found = fromTracer(t)
+		}
 
-	trace, ok := err.(tracer)
-	if ok {
-		return trace.Stack()
-	}
+		if found {
+			return walkStop
+		}
 
-	cause, ok := err.(causer)
-	if !ok {
-		return ""
-	}
+		return walkSkip
+	}, nil)
+}
+
+func Stack(err error) string {
+	var result string
 
-	return Stack(cause.Cause())
+	firstStack(err, func(s *stack) bool {
+		result = s.String()
+		return result != ""
+	}, func(t Tracer) bool {
+		result = t.Stack()
+		return result != ""
+	})
+
+	return result
 }
 
 func StackTrace(err error) []StackFrame {
-	if err == nil {
-		return nil
-	}
+	var result []StackFrame
 
-	trace, ok := err.(tracer)
-	if ok {
-		return trace.StackTrace()
-	}
-
-	cause, ok := err.(causer)
-	if !ok {
-		return nil
-	}
+	firstStack(err, func(s *stack) bool {
+		result = s.Frames()
+		return result != nil
+	}, func(t Tracer) bool {
+		result = t.StackTrace()
+		return result != nil
+	})
 
-	return StackTrace(cause.Cause())
+	return result
 }
 
-func Message(err error, defaultMessage ...string) string {
-	var msg string
+// StackTraces - получение всех стеков вызовов цепочки ошибок в порядке цепочки:
+// от внешней ошибки к внутренней, для мультиошибок - по порядку объединённых ошибок
+func StackTraces(err error) [][]StackFrame {
+	traces := make([][]StackFrame, 0)
+
+	walk(err, func(l *layer) walkAction {
+		var frames []StackFrame
+
+		switch {
+		case l.truncated != truncationNone:
+		case l.seg != nil:
+			if l.seg.kind == segmentStack {
+				frames = l.seg.stack.Frames()
+			}
+		default:
+			if t, ok := l.err.(Tracer); ok {
+				frames = t.StackTrace()
+			}
+		}
 
-	if err == nil {
-		if len(defaultMessage) > 1 {
-			msg = strings.Join(defaultMessage, " ")
-		} else if len(defaultMessage) > 0 {
-			msg = defaultMessage[0]
+		if len(frames) > 0 {
+			traces = append(traces, frames)
 		}
 
-		return msg
-	}
+		return walkNext
+	}, nil)
 
-	cause, ok := err.(causer)
-	if ok {
-		msg = messageRecursive(cause.Cause())
-	}
+	return traces
+}
 
-	e, ok := err.(messager)
-	if ok {
-		if e.Message() != "" && msg != "" {
-			msg = fmt.Sprintf("%s: %s", e.Message(), msg)
-		} else {
-			msg = e.Message()
-		}
-	}
+// StackTruncated - признак того, что стек, возвращаемый StackTrace, обрезан по MaxStackTraceDepth
+func StackTruncated(err error) bool {
+	var result bool
+
+	firstStack(err, func(s *stack) bool {
+		result = s.Truncated()
+		return s.Frames() != nil
+	}, func(t Tracer) bool {
+		e, ok := t.(interface{ StackTruncated() bool })
+		result = ok && e.StackTruncated()
+		return t.StackTrace() != nil
+	})
+
+	return result
+}
+
+func Message(err error, defaultMessage ...string) string {
+	msg := messageRecursive(err)
 
 	if msg == "" {
 		if len(defaultMessage) > 1 {
@@ -162,111 +389,171 @@ func Message(err error, defaultMessage ...string) string {
 	return msg
 }
 
+// messageRecursive - сообщение для пользователя всей цепочки:
+// сообщения уровней объединяются через ": ", ветки мультиошибки - через "; "
 func messageRecursive(err error) string {
-	if err == nil {
-		return ""
-	}
-
-	var msg string
-
-	cause, ok := err.(causer)
-	if ok {
-		msg = messageRecursive(cause.Cause())
-	}
-
-	e, ok := err.(messager)
-	if ok {
-		if e.Message() != "" && msg != "" {
-			msg = fmt.Sprintf("%s: %s", e.Message(), msg)
-		} else {
-			msg = e.Message()
+	return foldText(err, func(l *layer) (string, walkAction) {
+		switch {
+		case l.truncated != truncationNone:
+		case l.seg != nil:
+			if l.seg.kind == segmentMessage {
+				return l.seg.msg, walkNext
+			}
+		default:
+			if e, ok := l.err.(Messager); ok {
+				return e.Message(), walkNext
+			}
 		}
-	}
 
-	return msg
+		return "", walkNext
+	}, ": ")
 }
 
 func Messages(err error) []string {
 	msg := make([]string, 0)
 
-	messagesRecursive(err, &msg)
-
-	return msg
-}
-
-func messagesRecursive(err error, msg *[]string) {
-	if err == nil {
-		return
-	}
+	walk(err, func(l *layer) walkAction {
+		var v string
+
+		switch {
+		case l.truncated != truncationNone:
+		case l.seg != nil:
+			if l.seg.kind == segmentMessage {
+				v = l.seg.msg
+			}
+		default:
+			if e, ok := l.err.(Messager); ok {
+				v = e.Message()
+			}
+		}
 
-	e, ok := err.(messager)
-	if ok {
-		if e.Message() != "" {
-			*msg = append(*msg, e.Message())
+		if v != "" {
+			msg = append(msg, v)
 		}
-	}
 
-	cause, ok := err.(causer)
-	if ok {
-		messagesRecursive(cause.Cause(), msg)
-	}
+		return walkNext
+	}, nil)
 
-	return
+	return msg
 }
 
-func DevMessage(err error) string {
-	if err == nil {
-		return ""
-	}
-
-	var msg string
+// isForeignLeaf - проверка, что сторонняя ошибка не содержит ошибок errutil
+// и используется в dev-сообщениях своим текстовым представлением
+func (c *causerIndex) isForeignLeaf(err error) bool {
+	_, isMessager := err.(DevMessager)
 
-	cause, isCauser := err.(causer)
-	if isCauser {
-		msg = DevMessage(cause.Cause())
-	}
+	return !isMessager && !c.contains(err)
+}
 
-	e, isMessager := err.(devMessager)
-	if isMessager {
-		if e.DevMessage() != "" && msg != "" {
-			msg = fmt.Sprintf("%s, %s", e.DevMessage(), msg)
-		} else {
-			msg = e.DevMessage()
+// DevMessage - получение dev-сообщения цепочки ошибок.
+// Сторонние врапперы (fmt.Errorf("%w"), errors.Join) прозрачны, если содержат
+// внутри ошибки errutil, иначе используется их текстовое представление.
+// Оборванная цепочка (цикл, превышение Config.MaxChainDepth) отмечается в сообщении.
+func DevMessage(err error) string {
+	index := causerIndex{err: err}
+
+	return foldText(err, func(l *layer) (string, walkAction) {
+		switch {
+		case l.truncated != truncationNone:
+			return l.truncated.marker(), walkNext
+		case l.seg != nil:
+			if l.seg.kind == segmentDev {
+				return l.seg.devMessage(), walkNext
+			}
+		case index.isForeignLeaf(l.err):
+			return l.err.Error(), walkSkip
+		default:
+			if e, ok := l.err.(DevMessager); ok {
+				return e.DevMessage(), walkNext
+			}
 		}
-	}
-	if !isMessager && !isCauser {
-		return err.Error()
-	}
 
-	return msg
+		return "", walkNext
+	}, ", ")
 }
 
 func DevMessages(err error) []string {
 	msg := make([]string, 0)
+	index := causerIndex{err: err}
+
+	walk(err, func(l *layer) walkAction {
+		switch {
+		case l.truncated != truncationNone:
+			msg = append(msg, l.truncated.marker())
+		case l.seg != nil:
+			if l.seg.kind == segmentDev {
+				msg = append(msg, l.seg.dev...)
+			}
+		case index.isForeignLeaf(l.err):
+			msg = append(msg, l.err.Error())
+			return walkSkip
+		default:
+			if e, ok := l.err.(DevMessager); ok {
+				msg = append(msg, e.DevMessages()...)
+			}
+		}
 
-	devMessagesRecursive(err, &msg)
+		return walkNext
+	}, nil)
 
 	return msg
 }
 
-func devMessagesRecursive(err error, msg *[]string) {
-	if err == nil {
-		return
-	}
+// Fields - получение структурированных полей всей цепочки ошибок.
+// Поля объединяются от внутренних ошибок к внешним: при совпадении ключей
+// значение внешней ошибки заменяет значение внутренней.
+func Fields(err error) map[string]any {
+	fields := fold(err, func(l *layer) (map[string]any, bool, walkAction) {
+		switch {
+		case l.truncated != truncationNone:
+		case l.seg != nil:
+			if l.seg.kind == segmentFields {
+				return l.seg.fields, true, walkNext
+			}
+		default:
+			if e, ok := l.err.(Fielder); ok {
+				return e.Fields(), true, walkNext
+			}
+		}
 
-	cause, isCauser := err.(causer)
+		return nil, false, walkNext
+	}, func(chain []map[string]any) (map[string]any, bool) {
+		merged := make(map[string]any)
+		for i := len(chain) - 1; i >= 0; i-- {
+			maps.Copy(merged, chain[i])
+		}
 
-	e, isMessager := err.(devMessager)
-	if isMessager {
-		*msg = slices.Concat(*msg, e.DevMessages())
-	}
-	if !isMessager && !isCauser {
-		*msg = append(*msg, err.Error())
-	}
+		return merged, len(chain) > 0
+	}, func(branches []map[string]any) (map[string]any, bool) {
+		merged := make(map[string]any)
+		for _, fields := range branches {
+			maps.Copy(merged, fields)
+		}
 
-	if isCauser {
-		devMessagesRecursive(cause.Cause(), msg)
+		return merged, len(branches) > 0
+	})
+
+	if fields == nil {
+		fields = make(map[string]any)
 	}
 
-	return
+	return fields
+}
+
+// foldText - свёртка текстов уровней цепочки: непустые тексты ветки объединяются
+// через sep, непустые результаты веток мультиошибки - через "; "
+func foldText(err error, text func(l *layer) (string, walkAction), sep string) string {
+	return fold(err, func(l *layer) (string, bool, walkAction) {
+		v, action := text(l)
+
+		return v, v != "", action
+	}, func(parts []string) (string, bool) {
+		v := strings.Join(parts, sep)
+
+		return v, v != ""
+	}, func(parts []string) (string, bool) {
+		v := strings.Join(parts, "; ")
+
+		return v, v != ""
+	})
 }
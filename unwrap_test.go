@@ -1,10 +1,14 @@
 package errutil_test
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/kontora13-go/errutil"
+	"io"
 	"log"
+	"slices"
 	"testing"
 )
 
@@ -60,3 +64,63 @@ func newErrorStackWithDepth(err error, depth int) error {
 	depth--
 	return newErrorStackWithDepth(err, depth)
 }
+
+func TestUnwrapStdErrors(t *testing.T) {
+	root := sql.ErrNoRows
+
+	err := errutil.WithCode(root, "DB")
+	err = errutil.WithDevMessage(err, "select order")
+	err = errutil.WithMessage(err, "Заказ не найден")
+	err = fmt.Errorf("handler: %w", err)
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Error("errors.Is: root cause not found")
+	}
+	if !errors.Is(err, errutil.NewWithCode("DB")) {
+		t.Error("errors.Is: code DB not matched")
+	}
+	if errors.Is(err, errutil.NewWithCode("OTHER")) {
+		t.Error("errors.Is: unexpected match by code OTHER")
+	}
+	if errors.Is(errutil.New("test"), io.EOF) {
+		t.Error("errors.Is: unexpected match with foreign error")
+	}
+
+	if got := errutil.Code(err); got != "DB" {
+		t.Errorf("Code() = %q, want %q", got, "DB")
+	}
+	if got := errutil.Message(err); got != "Заказ не найден" {
+		t.Errorf("Message() = %q", got)
+	}
+	if got := errutil.DevMessage(err); got != "select order, sql: no rows in result set" {
+		t.Errorf("DevMessage() = %q", got)
+	}
+	if got := errutil.Cause(err); got != sql.ErrNoRows {
+		t.Errorf("Cause() = %v", got)
+	}
+}
+
+func TestUnwrapJoin(t *testing.T) {
+	err := errors.Join(
+		fmt.Errorf("plain"),
+		errutil.WithMessage(errutil.NewWithCode("USER", "validation"), "Неверный запрос"),
+	)
+
+	if got := errutil.Code(err); got != "USER" {
+		t.Errorf("Code() = %q, want %q", got, "USER")
+	}
+	if got := errutil.Message(err); got != "Неверный запрос" {
+		t.Errorf("Message() = %q", got)
+	}
+	if got := errutil.DevMessages(err); !slices.Equal(got, []string{"plain", "validation"}) {
+		t.Errorf("DevMessages() = %q", got)
+	}
+	if stackEnabled && errutil.Stack(err) == "" {
+		t.Error("Stack() is empty")
+	}
+
+	var target interface{ Code() string }
+	if !errors.As(err, &target) {
+		t.Error("errors.As: coder not found")
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2024-2025 Kontora13. All rights reserved.
+// Licensed under the Apache License, Version 2.0
+
+// Итеративный обход цепочки ошибок с защитой от циклов и ограничением глубины
+
+package errutil
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// truncation - причина обрыва обхода цепочки ошибок
+type truncation uint8
+
+const (
+	truncationNone truncation = iota
+	truncationCycle
+	truncationDepth
+)
+
+// String - получение текстового представления причины обрыва
+func (t truncation) String() string {
+	switch t {
+	case truncationCycle:
+		return "cycle"
+	case truncationDepth:
+		return "max depth"
+	}
+
+	return ""
+}
+
+// marker - пометка об обрыве цепочки для текстовых представлений ошибки
+func (t truncation) marker() string {
+	return fmt.Sprintf("...error chain truncated (%s)...", t)
+}
+
+// layer - уровень цепочки ошибок, посещаемый при обходе
+type layer struct {
+	// Ошибка уровня: сторонняя ошибка или узел errutil, содержащий сегмент
+	err error
+
+	// Сегмент узла errutil и его индекс, nil для сторонней ошибки
+	seg   *segment
+	index int
+
+	// Следующая ошибка цепочки и ошибки мультиошибки сторонней ошибки
+	next  error
+	multi []error
+
+	// Причина обрыва цепочки на этом уровне
+	truncated truncation
+}
+
+// node - узел errutil уровня-сегмента
+func (l *layer) node() *node {
+	e, _ := l.err.(*node)
+
+	return e
+}
+
+// walkAction - действие после посещения уровня
+type walkAction uint8
+
+const (
+	// Переход к следующему уровню, для мультиошибки - к её ошибкам
+	walkNext walkAction = iota
+
+	// Пропуск оставшихся уровней текущей ветки
+	walkSkip
+
+	// Завершение обхода
+	walkStop
+)
+
+// walkEvent - событие обхода ветвей мультиошибки
+type walkEvent uint8
+
+const (
+	walkMultiStart walkEvent = iota
+	walkBranchStart
+	walkBranchEnd
+	walkMultiEnd
+)
+
+// walk - обход цепочки ошибок от внешнего уровня к внутреннему без рекурсии.
+// Сегменты узлов errutil посещаются как отдельные уровни, ошибки мультиошибок -
+// по порядку как ветки, обрамлённые событиями event (может быть nil).
+// Уровень, переданный в visit, действителен только до возврата из visit.
+// При обнаружении цикла или превышении Config.MaxChainDepth посещается уровень
+// с признаком truncated, после чего ветка завершается. Глубина берётся из экземпляра
+// первого узла errutil цепочки, созданного через Factory, до этого узла - из Default.
+func walk(err error, visit func(l *layer) walkAction, event func(e walkEvent, err error)) {
+	w := walker{
+		visit:    visit,
+		event:    event,
+		maxDepth: Default().maxChainDepth(),
+	}
+
+	w.walk(err)
+}
+
+// walker - состояние обхода цепочки ошибок
+type walker struct {
+	visit    func(l *layer) walkAction
+	event    func(e walkEvent, err error)
+	maxDepth int
+
+	// Признак завершения поиска экземпляра, через который создана ошибка
+	resolved bool
+
+	// Стек мультиошибок, ветки которых обходятся
+	stack []branches
+
+	// Текущий уровень, переиспользуется между вызовами visit
+	layer layer
+}
+
+// branches - мультиошибка и её ветки, ожидающие обхода
+type branches struct {
+	multi error
+	errs  []error
+	depth int
+}
+
+func (w *walker) walk(err error) {
+	action, multi := w.chain(err, 0)
+	for action != walkStop {
+		if multi != nil {
+			w.emit(walkMultiStart, nil)
+			w.stack = append(w.stack, *multi)
+		} else if len(w.stack) > 0 {
+			w.emit(walkBranchEnd, nil)
+		}
+
+		for len(w.stack) > 0 && len(w.stack[len(w.stack)-1].errs) == 0 {
+			w.stack = w.stack[:len(w.stack)-1]
+			w.emit(walkMultiEnd, nil)
+			if len(w.stack) > 0 {
+				w.emit(walkBranchEnd, nil)
+			}
+		}
+		if len(w.stack) == 0 {
+			return
+		}
+
+		top := &w.stack[len(w.stack)-1]
+		next := top.errs[0]
+		top.errs = top.errs[1:]
+
+		w.emit(walkBranchStart, next)
+		action, multi = w.chain(next, top.depth)
+	}
+}
+
+// chain - обход одной ветки до её конца или до мультиошибки,
+// ветки которой возвращаются для дальнейшего обхода
+func (w *walker) chain(err error, depth int) (walkAction, *branches) {
+	cycle := cycleDetector{}
+
+	for err != nil {
+		if v, ok := err.(viewer); ok {
+			err = v.viewNode()
+		}
+		if e, ok := err.(*node); ok {
+			if !w.resolved && e.factory != nil {
+				w.maxDepth = e.factory.maxChainDepth()
+				w.resolved = true
+			}
+
+			for i := len(e.segs) - 1; i >= 0; i-- {
+				if depth >= w.maxDepth {
+					return w.truncate(err, truncationDepth), nil
+				}
+				depth++
+
+				w.layer = layer{err: e, seg: e.segs[i], index: i}
+				if action := w.visit(&w.layer); action != walkNext {
+					return action, nil
+				}
+			}
+
+			err = e.root()
+			continue
+		}
+
+		if depth >= w.maxDepth {
+			return w.truncate(err, truncationDepth), nil
+		}
+		if cycle.seen(err) {
+			return w.truncate(err, truncationCycle), nil
+		}
+		depth++
+
+		next, multi := unwrapOnce(err)
+		if multi != nil && w.isAncestor(err) {
+			return w.truncate(err, truncationCycle), nil
+		}
+		w.layer = layer{err: err, next: next, multi: multi}
+		if action := w.visit(&w.layer); action != walkNext {
+			return action, nil
+		}
+		if multi != nil {
+			w.resolved = true
+			return walkNext, &branches{multi: err, errs: multi, depth: depth}
+		}
+
+		err = next
+	}
+
+	return walkNext, nil
+}
+
+// isAncestor - проверка, что мультиошибка err уже обходится выше по цепочке
+func (w *walker) isAncestor(err error) bool {
+	if !reflect.ValueOf(err).Comparable() {
+		return false
+	}
+
+	for _, b := range w.stack {
+		if reflect.ValueOf(b.multi).Comparable() && b.multi == err {
+			return true
+		}
+	}
+
+	return false
+}
+
+// truncate - посещение уровня обрыва цепочки
+func (w *walker) truncate(err error, reason truncation) walkAction {
+	w.layer = layer{err: err, truncated: reason}
+	if w.visit(&w.layer) == walkStop {
+		return walkStop
+	}
+
+	return walkNext
+}
+
+func (w *walker) emit(e walkEvent, err error) {
+	if w.event != nil {
+		w.event(e, err)
+	}
+}
+
+// cycleDetector - обнаружение цикла в последовательности ошибок алгоритмом Брента.
+// Сравниваются только сравнимые значения ошибок, остальные пропускаются.
+type cycleDetector struct {
+	tortoise error
+	power    int
+	steps    int
+}
+
+// seen - проверка повторения ошибки err в последовательности
+func (c *cycleDetector) seen(err error) bool {
+	if !reflect.ValueOf(err).Comparable() {
+		return false
+	}
+
+	if c.tortoise != nil && c.tortoise == err {
+		return true
+	}
+
+	if c.steps == c.power {
+		c.tortoise = err
+		c.power = max(c.power*2, 1)
+		c.steps = 0
+	}
+	c.steps++
+
+	return false
+}
+
+// fold - свёртка значений уровней цепочки ошибок: значения одной ветки
+// объединяются через chainJoin (от внешнего уровня к внутреннему),
+// результаты веток мультиошибки - через multiJoin.
+// value возвращает значение уровня, признак его наличия и действие обхода.
+func fold[T any](err error, value func(l *layer) (T, bool, walkAction), chainJoin func([]T) (T, bool), multiJoin func([]T) (T, bool)) T {
+	chains := [][]T{nil}
+	var multis [][]T
+
+	walk(err, func(l *layer) walkAction {
+		v, ok, action := value(l)
+		if ok {
+			chains[len(chains)-1] = append(chains[len(chains)-1], v)
+		}
+
+		return action
+	}, func(e walkEvent, _ error) {
+		switch e {
+		case walkMultiStart:
+			multis = append(multis, nil)
+		case walkBranchStart:
+			chains = append(chains, nil)
+		case walkBranchEnd:
+			v, ok := chainJoin(chains[len(chains)-1])
+			chains = chains[:len(chains)-1]
+			if ok {
+				multis[len(multis)-1] = append(multis[len(multis)-1], v)
+			}
+		case walkMultiEnd:
+			v, ok := multiJoin(multis[len(multis)-1])
+			multis = multis[:len(multis)-1]
+			if ok {
+				chains[len(chains)-1] = append(chains[len(chains)-1], v)
+			}
+		}
+	})
+
+	v, _ := chainJoin(chains[0])
+
+	return v
+}
@@ -0,0 +1,173 @@
+package errutil_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kontora13-go/errutil"
+)
+
+// selfCause - ошибка, возвращающая себя как причину
+type selfCause struct{}
+
+func (e *selfCause) Error() string { return "self" }
+func (e *selfCause) Cause() error  { return e }
+
+// selfJoin - мультиошибка, содержащая себя
+type selfJoin struct {
+	errs []error
+}
+
+func (e *selfJoin) Error() string   { return "join" }
+func (e *selfJoin) Unwrap() []error { return e.errs }
+
+// layerError - сторонняя ошибка-обёртка без текста причины
+type layerError struct {
+	err error
+}
+
+func (e *layerError) Error() string { return "layer" }
+func (e *layerError) Cause() error  { return e.err }
+
+// unwrapError - сторонняя ошибка-обёртка с постоянным текстом
+type unwrapError struct {
+	err error
+}
+
+func (e *unwrapError) Error() string { return "unwrap" }
+func (e *unwrapError) Unwrap() error { return e.err }
+
+func TestWalkCycle(t *testing.T) {
+	join := &selfJoin{}
+	join.errs = []error{errutil.New("first"), join}
+
+	// Сторонние ветки мультиошибки выводятся в %+v как есть, без обхода
+	tests := []struct {
+		name    string
+		err     error
+		verbose bool
+	}{
+		{"Cause", errutil.WithDevMessage(&selfCause{}, "dev"), true},
+		{"Join", errutil.WithDevMessage(join, "dev"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if dev := errutil.DevMessage(tt.err); !strings.Contains(dev, "truncated (cycle)") {
+				t.Errorf("DevMessage() = %q, want cycle marker", dev)
+			}
+			if text := fmt.Sprintf("%+v", tt.err); tt.verbose && !strings.Contains(text, "truncated: cycle") {
+				t.Errorf("verbose format without cycle marker:\n%s", text)
+			}
+			if code := errutil.Code(tt.err); code != errutil.DefaultCode {
+				t.Errorf("Code() = %q", code)
+			}
+			if _, err := json.Marshal(tt.err); err != nil {
+				t.Errorf("json.Marshal() error = %v", err)
+			}
+			_ = errutil.Cause(tt.err)
+			_ = errutil.Messages(tt.err)
+			_ = errutil.StackTraces(tt.err)
+		})
+	}
+}
+
+func TestWalkMaxDepth(t *testing.T) {
+	defer errutil.SetDefault(nil)
+	errutil.SetDefault(errutil.NewFactory(errutil.Config{MaxChainDepth: 5}))
+
+	err := errutil.NewWithCode(errutil.CodeUser, "root")
+	for i := range 10 {
+		err = errutil.WithDevMessagef(err, "dev %d", i)
+	}
+
+	if dev := errutil.DevMessage(err); !strings.HasSuffix(dev, "...error chain truncated (max depth)...") {
+		t.Errorf("DevMessage() = %q", dev)
+	}
+	if devs := errutil.DevMessages(err); len(devs) != 6 {
+		t.Errorf("DevMessages() = %q, want 5 messages and marker", devs)
+	}
+	// Код находится за пределами обходимой глубины
+	if code := errutil.Code(err); code != errutil.DefaultCode {
+		t.Errorf("Code() = %q, want %q", code, errutil.DefaultCode)
+	}
+
+	data, err := json.Marshal(err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded error
+	if err := errutil.Decode(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(errutil.DevMessage(decoded), "truncated (max depth)") {
+		t.Errorf("decoded DevMessage() = %q", errutil.DevMessage(decoded))
+	}
+}
+
+func TestWalkDeepChain(t *testing.T) {
+	var err error = errors.New("root")
+	for range 1_000_000 {
+		err = &layerError{err: err}
+	}
+	err = errutil.WithDevMessage(err, "dev")
+
+	if dev := errutil.DevMessage(err); !strings.Contains(dev, "truncated (max depth)") {
+		t.Errorf("DevMessage() = %q", dev)
+	}
+	if text := fmt.Sprintf("%+v", err); !strings.Contains(text, "truncated: max depth") {
+		t.Error("verbose format without max depth marker")
+	}
+	_ = errutil.Code(err)
+	_ = errutil.Stack(err)
+}
+
+func TestWalkFactoryMaxDepth(t *testing.T) {
+	f := errutil.NewFactory(errutil.Config{MaxChainDepth: 3})
+
+	err := f.NewWithCode(errutil.CodeUser, "root")
+	for i := range 5 {
+		err = errutil.WithDevMessagef(err, "dev %d", i)
+	}
+
+	// Глубина берётся из экземпляра, через который создана ошибка, а не из Default
+	if devs := errutil.DevMessages(err); len(devs) != 4 || devs[3] != "...error chain truncated (max depth)..." {
+		t.Errorf("DevMessages() = %q, want 3 messages and marker", devs)
+	}
+	if devs := errutil.DevMessages(errutil.WithDevMessage(errutil.New("root"), "dev")); len(devs) != 2 {
+		t.Errorf("package DevMessages() = %q", devs)
+	}
+}
+
+func TestWalkForeignChain(t *testing.T) {
+	err := errutil.NewWithCode(errutil.CodeUser, "root")
+	for range 5000 {
+		err = &unwrapError{err: err}
+	}
+	err = errutil.WithMessage(err, "msg")
+
+	if dev := errutil.DevMessage(err); dev != "root" {
+		t.Errorf("DevMessage() = %q", dev)
+	}
+	if text := fmt.Sprintf("%+v", err); strings.Count(text, "wrapped: ") != 5000 {
+		t.Error("verbose format must contain every foreign layer")
+	}
+	if _, err := json.Marshal(errutil.JSONError{Err: err}); err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+}
+
+func TestWalkLongDevChain(t *testing.T) {
+	err := errutil.New("root")
+	for i := range 5000 {
+		err = errutil.WithDevMessagef(err, "dev %d", i)
+	}
+
+	devs := errutil.DevMessages(err)
+	if len(devs) != 5001 || devs[0] != "dev 4999" || devs[5000] != "root" {
+		t.Errorf("DevMessages() has %d messages", len(devs))
+	}
+}
@@ -6,83 +6,39 @@ import (
 )
 
 func WithCode(err error, code string) error {
-	if err == nil {
-		return &errWithStack{
-			cause:      err,
-			code:       code,
-			stacktrace: newErrorStack(),
-		}
-	}
-
-	return &errWithCode{
-		cause: err,
-		code:  code,
-	}
+	return Default().withCode(1, err, code)
 }
 
 func WithStack(err error) error {
-	return &errWithStack{
-		cause:      err,
-		stacktrace: newErrorStack(),
-	}
+	return Default().withStack(1, err)
 }
 
-func WithMessage(err error, msg ...string) error {
-	if err == nil {
-		err = &errWithStack{
-			code:       DefaultCode,
-			cause:      err,
-			stacktrace: newErrorStack(),
-		}
-	}
+// WithStackSkip - добавление стека вызовов с пропуском skip дополнительных
+// фреймов над вызывающей функцией (например, для функций-обёрток)
+func WithStackSkip(err error, skip int) error {
+	return Default().withStack(1+max(skip, 0), err)
+}
 
-	return &errWithMessage{
-		cause: err,
-		msg:   strings.Join(msg, ": "),
-	}
+func WithMessage(err error, msg ...string) error {
+	return Default().withMessage(1, err, strings.Join(msg, ": "))
 }
 
 func WithMessagef(err error, format string, args ...interface{}) error {
-	if err == nil {
-		err = &errWithStack{
-			code:       DefaultCode,
-			cause:      err,
-			stacktrace: newErrorStack(),
-		}
-	}
-
-	return &errWithMessage{
-		cause: err,
-		msg:   fmt.Sprintf(format, args...),
-	}
+	return Default().withMessage(1, err, fmt.Sprintf(format, args...))
 }
 
 func WithDevMessage(err error, msg ...string) error {
-	if err == nil {
-		err = &errWithStack{
-			code:       DefaultCode,
-			cause:      err,
-			stacktrace: newErrorStack(),
-		}
-	}
-
-	return &errWithDevMessage{
-		cause: err,
-		dev:   msg,
-	}
+	return Default().withDevMessage(1, err, msg)
 }
 
 func WithDevMessagef(err error, format string, args ...interface{}) error {
-	if err == nil {
-		err = &errWithStack{
-			code:       DefaultCode,
-			cause:      err,
-			stacktrace: newErrorStack(),
-		}
-	}
+	return Default().withDevMessage(1, err, []string{fmt.Sprintf(format, args...)})
+}
+
+func WithField(err error, key string, value any) error {
+	return Default().withFields(1, err, map[string]any{key: value})
+}
 
-	return &errWithDevMessage{
-		cause: err,
-		dev:   []string{fmt.Sprintf(format, args...)},
-	}
+func WithFields(err error, fields map[string]any) error {
+	return Default().withFields(1, err, fields)
 }
@@ -1,8 +1,13 @@
 package errutil_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/kontora13-go/errutil"
@@ -51,3 +56,36 @@ func TestWrapInGoroutine(t *testing.T) {
 	log.Print("err := ", err.Error())
 	log.Print("stack_trace := ", errutil.Stack(err))
 }
+
+func TestWrapFields(t *testing.T) {
+	err := errutil.WithField(errutil.New("select order"), "order_id", 42)
+	err = errutil.WithFields(err, map[string]any{"user_id": "u1", "order_id": 43})
+	err = errutil.WithMessage(err, "Заказ не найден")
+
+	fields := errutil.Fields(err)
+	if len(fields) != 2 || fields["order_id"] != 43 || fields["user_id"] != "u1" {
+		t.Errorf("Fields() = %v", fields)
+	}
+
+	if verbose := fmt.Sprintf("%+v", err); !strings.Contains(verbose, "fields: order_id=43 user_id=u1\n") {
+		t.Errorf("%%+v does not contain fields:\n%s", verbose)
+	}
+
+	data, e := json.Marshal(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var decoded error
+	if e := errutil.Decode(data, &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if fields = errutil.Fields(decoded); fields["user_id"] != "u1" || fields["order_id"] != float64(43) {
+		t.Errorf("decoded Fields() = %v", fields)
+	}
+
+	buf := bytes.Buffer{}
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("failed", "err", err)
+	if !strings.Contains(buf.String(), "err.fields.order_id=43 err.fields.user_id=u1") {
+		t.Errorf("slog record = %s", buf.String())
+	}
+}